@@ -0,0 +1,170 @@
+package rqp
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// tagName is a name of struct tag for BindToStruct
+const tagName = "rqp"
+
+// BindToStruct populates fields of struct pointed by dest from parsed Query.
+// Fields are matched by tag `rqp:"name"`, fields without the tag are skipped.
+// Special names are:
+//
+//	limit, offset - int fields
+//	fields - []string field
+//	sort - []Sort or []string field (eg. ["-id", "name"])
+//
+// Other names are names of filters, value of the first filter with the name is set
+// if its type is assignable to the field or to the element of pointer field.
+// Fields of filters which aren't present in Query are left untouched.
+// Returns ErrBadFormat if dest isn't a pointer to struct or types mismatch.
+//
+// Example:
+//
+//	type SearchParams struct {
+//	    ID    int    `rqp:"id"`
+//	    Email string `rqp:"email"`
+//	    Limit int    `rqp:"limit"`
+//	}
+//	var params SearchParams
+//	err := q.BindToStruct(&params)
+func (q *Query) BindToStruct(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Wrap(ErrBadFormat, "dest must be a pointer to struct")
+	}
+	rv = rv.Elem()
+
+	for i := 0; i < rv.NumField(); i++ {
+		name, ok := rv.Type().Field(i).Tag.Lookup(tagName)
+		if !ok || name == "" || name == "-" {
+			continue
+		}
+
+		var value interface{}
+		switch name {
+		case "limit":
+			value = q.Limit
+		case "offset":
+			value = q.Offset
+		case "fields":
+			value = q.Fields
+		case "sort":
+			if rv.Field(i).Type() == reflect.TypeOf([]string(nil)) {
+				sorts := make([]string, len(q.Sorts))
+				for j, s := range q.Sorts {
+					sorts[j] = s.By
+					if s.Desc {
+						sorts[j] = "-" + s.By
+					}
+				}
+				value = sorts
+			} else {
+				value = q.Sorts
+			}
+		default:
+			f, err := q.GetFilter(name)
+			if err != nil {
+				continue
+			}
+			value = f.Value
+		}
+
+		if err := setField(rv.Field(i), value); err != nil {
+			return errors.Wrap(err, name)
+		}
+	}
+
+	return nil
+}
+
+// NewFromStruct creates new Query instance from fields of struct tagged by `rqp:"name"`
+// and parses it with validations like NewParse does, so the struct
+//
+//	SearchParams{ID: 5, Limit: 20}
+//
+// gives the same Query as URL `?id=5&limit=20`. Zero-value fields are omitted.
+// Name of filter in tag could contain method, eg. `rqp:"id[gte]"`,
+// slices are IN filters by default. Tag names are the same as for BindToStruct.
+func NewFromStruct(params interface{}, v Validations) (*Query, error) {
+	rv := reflect.ValueOf(params)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.Wrap(ErrBadFormat, "params must be a struct or a pointer to struct")
+	}
+
+	query := New().SetValidations(v)
+
+	values := url.Values{}
+	for i := 0; i < rv.NumField(); i++ {
+		name, ok := rv.Type().Field(i).Tag.Lookup(tagName)
+		if !ok || name == "" || name == "-" {
+			continue
+		}
+
+		field := rv.Field(i)
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if field.IsZero() || (field.Kind() == reflect.Slice && field.Len() == 0) {
+			continue
+		}
+
+		if field.Kind() != reflect.Slice {
+			values.Add(name, fmt.Sprint(field.Interface()))
+			continue
+		}
+
+		list := make([]string, field.Len())
+		for j := range list {
+			elem := field.Index(j).Interface()
+			if s, ok := elem.(Sort); ok {
+				list[j] = s.By
+				if s.Desc {
+					list[j] = "-" + s.By
+				}
+			} else {
+				list[j] = fmt.Sprint(elem)
+			}
+		}
+		if name != "fields" && name != "sort" && !strings.Contains(name, "[") {
+			name += "[in]"
+		}
+		values.Add(name, strings.Join(list, query.delimiterIN))
+	}
+
+	return query, query.ParseValues(values)
+}
+
+// setField sets value to field or to element of pointer field
+func setField(field reflect.Value, value interface{}) error {
+	v := reflect.ValueOf(value)
+	if !field.CanSet() || !v.IsValid() {
+		return ErrBadFormat
+	}
+
+	if v.Type().AssignableTo(field.Type()) {
+		field.Set(v)
+		return nil
+	}
+
+	if field.Kind() == reflect.Ptr && v.Type().AssignableTo(field.Type().Elem()) {
+		p := reflect.New(field.Type().Elem())
+		p.Elem().Set(v)
+		field.Set(p)
+		return nil
+	}
+
+	return ErrBadFormat
+}
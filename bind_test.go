@@ -0,0 +1,125 @@
+package rqp
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_BindToStruct(t *testing.T) {
+	type SearchParams struct {
+		ID       int      `rqp:"id"`
+		IDs      []int    `rqp:"ids"`
+		Email    *string  `rqp:"email"`
+		Active   bool     `rqp:"active"`
+		Missing  string   `rqp:"missing"`
+		Fields   []string `rqp:"fields"`
+		Sort     []string `rqp:"sort"`
+		Sorts    []Sort   `rqp:"sort"`
+		Limit    int      `rqp:"limit"`
+		Offset   int      `rqp:"offset"`
+		Untagged string
+	}
+
+	URL, _ := url.Parse("/?id=5&ids[in]=1,2&email[like]=*tim*&active=true&fields=id&sort=-id,name&limit=10&offset=20")
+	q, err := NewParse(URL.Query(), Validations{
+		"id:int":      nil,
+		"ids:int":     nil,
+		"email":       nil,
+		"active:bool": nil,
+		"missing":     nil,
+		"fields":      In("id"),
+		"sort":        In("id", "name"),
+	})
+	assert.NoError(t, err)
+
+	params := SearchParams{Missing: "default", Untagged: "untouched"}
+	assert.NoError(t, q.BindToStruct(&params))
+
+	email := "*tim*"
+	assert.Equal(t, SearchParams{
+		ID:       5,
+		IDs:      []int{1, 2},
+		Email:    &email,
+		Active:   true,
+		Missing:  "default",
+		Fields:   []string{"id"},
+		Sort:     []string{"-id", "name"},
+		Sorts:    []Sort{{By: "id", Desc: true}, {By: "name"}},
+		Limit:    10,
+		Offset:   20,
+		Untagged: "untouched",
+	}, params)
+
+	t.Run("type mismatch", func(t *testing.T) {
+		var bad struct {
+			ID string `rqp:"id"`
+		}
+		err := q.BindToStruct(&bad)
+		assert.Equal(t, ErrBadFormat, errors.Cause(err))
+		assert.EqualError(t, err, "id: bad format")
+	})
+
+	t.Run("not a pointer to struct", func(t *testing.T) {
+		assert.Equal(t, ErrBadFormat, errors.Cause(q.BindToStruct(params)))
+		assert.Equal(t, ErrBadFormat, errors.Cause(q.BindToStruct((*SearchParams)(nil))))
+		var i int
+		assert.Equal(t, ErrBadFormat, errors.Cause(q.BindToStruct(&i)))
+	})
+}
+
+func TestNewFromStruct(t *testing.T) {
+	type SearchParams struct {
+		ID     int      `rqp:"id"`
+		MinAge *int     `rqp:"age[gte]"`
+		IDs    []int    `rqp:"ids"`
+		Email  string   `rqp:"email[like]"`
+		Active bool     `rqp:"active"`
+		Fields []string `rqp:"fields"`
+		Sort   []Sort   `rqp:"sort"`
+		Limit  int      `rqp:"limit"`
+		Offset int      `rqp:"offset"`
+		Skip   string
+	}
+	validations := Validations{
+		"id:int":      nil,
+		"age:int":     nil,
+		"ids:int":     nil,
+		"email":       nil,
+		"active:bool": nil,
+		"fields":      In("id", "email"),
+		"sort":        In("id", "email"),
+	}
+
+	q, err := NewFromStruct(&SearchParams{ID: 5, Limit: 20, Skip: "skip"}, validations)
+	assert.NoError(t, err)
+	URL, _ := url.Parse("/?id=5&limit=20")
+	expected, err := NewParse(URL.Query(), validations)
+	assert.NoError(t, err)
+	assert.True(t, expected.Equals(q))
+
+	age := 18
+	q, err = NewFromStruct(SearchParams{
+		MinAge: &age,
+		IDs:    []int{1, 2},
+		Email:  "*tim*",
+		Active: true,
+		Fields: []string{"id", "email"},
+		Sort:   []Sort{{By: "id", Desc: true}, {By: "email"}},
+		Offset: 10,
+	}, validations)
+	assert.NoError(t, err)
+	URL, _ = url.Parse("/?age[gte]=18&ids[in]=1,2&email[like]=*tim*&active=true&fields=id,email&sort=-id,email&offset=10")
+	expected, err = NewParse(URL.Query(), validations)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Hash(), q.Hash())
+
+	// validations are applied
+	_, err = NewFromStruct(SearchParams{Fields: []string{"password"}}, validations)
+	assert.Equal(t, ErrNotInScope, errors.Cause(err))
+
+	_, err = NewFromStruct(1, validations)
+	assert.Equal(t, ErrBadFormat, errors.Cause(err))
+}
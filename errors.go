@@ -26,4 +26,5 @@ var (
 	ErrFilterNotAllowed   = NewError("filter are not allowed")
 	ErrFilterNotFound     = NewError("filter not found")
 	ErrValidationNotFound = NewError("validation not found")
+	ErrInvalidCharacter   = NewError("invalid character")
 )
@@ -11,10 +11,13 @@ import (
 // this func used only for IN statements
 // https://github.com/jmoiron/sqlx
 
-// in expands slice values in args, returning the modified query string
+// ExpandIn expands slice values in args, returning the modified query string
 // and a new arg list that can be executed by a database. The `query` should
 // use the `?` bindVar.  The return value uses the `?` bindVar.
-func in(query string, args ...interface{}) (string, []interface{}, error) {
+// It's named ExpandIn because the name In is taken by the validation func.
+//
+// Example: ExpandIn("id IN (?)", []int{1, 2}) returns "id IN (?, ?)" and [1 2].
+func ExpandIn(query string, args ...interface{}) (string, []interface{}, error) {
 	// argMeta stores reflect.Value and length for slices and
 	// the value itself for non-slice arguments
 	type argMeta struct {
@@ -120,6 +123,18 @@ func appendReflectSlice(args []interface{}, v reflect.Value, vlen int) []interfa
 		for i := range val {
 			args = append(args, val[i])
 		}
+	case []int64:
+		for i := range val {
+			args = append(args, val[i])
+		}
+	case []float64:
+		for i := range val {
+			args = append(args, val[i])
+		}
+	case []bool:
+		for i := range val {
+			args = append(args, val[i])
+		}
 	default:
 		for si := 0; si < vlen; si++ {
 			args = append(args, v.Index(si).Interface())
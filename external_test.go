@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,52 +16,75 @@ func (v MyValuer) Value() (driver.Value, error) {
 	return "NULL", nil
 }
 
-func Test_in(t *testing.T) {
+func TestExpandIn(t *testing.T) {
 	t.Run("ALL OK", func(t *testing.T) {
-		q, args, err := in("id IN (?)", []string{"1", "2"})
+		q, args, err := ExpandIn("id IN (?)", []string{"1", "2"})
 		assert.NoError(t, err)
 		assert.Equal(t, "id IN (?, ?)", q)
 		assert.Equal(t, []interface{}{"1", "2"}, args)
 	})
 
+	t.Run("Typed slices", func(t *testing.T) {
+		now := time.Now()
+		id := uuid.New()
+		cases := []struct {
+			name string
+			arg  interface{}
+			want []interface{}
+		}{
+			{name: "[]int", arg: []int{1, 2}, want: []interface{}{1, 2}},
+			{name: "[]int64", arg: []int64{1, 2}, want: []interface{}{int64(1), int64(2)}},
+			{name: "[]float64", arg: []float64{1.5, 2}, want: []interface{}{1.5, float64(2)}},
+			{name: "[]bool", arg: []bool{true, false}, want: []interface{}{true, false}},
+			{name: "[]time.Time", arg: []time.Time{now, now}, want: []interface{}{now, now}},
+			{name: "[]uuid.UUID", arg: []uuid.UUID{id, id}, want: []interface{}{id, id}},
+		}
+		for _, c := range cases {
+			q, args, err := ExpandIn("id IN (?)", c.arg)
+			assert.NoError(t, err, c.name)
+			assert.Equal(t, "id IN (?, ?)", q, c.name)
+			assert.Equal(t, c.want, args, c.name)
+		}
+	})
+
 	t.Run("Valuer", func(t *testing.T) {
-		q, args, err := in("id IN (?)", []sql.NullString{{String: "1", Valid: true}, {String: "2"}})
+		q, args, err := ExpandIn("id IN (?)", []sql.NullString{{String: "1", Valid: true}, {String: "2"}})
 		assert.NoError(t, err)
 		assert.Equal(t, "id IN (?, ?)", q)
 		assert.Equal(t, []interface{}{sql.NullString{String: "1", Valid: true}, sql.NullString{String: "2", Valid: false}}, args)
 	})
 
 	t.Run("MyValuer", func(t *testing.T) {
-		q, args, err := in("id IN (?)", MyValuer{})
+		q, args, err := ExpandIn("id IN (?)", MyValuer{})
 		assert.NoError(t, err)
 		assert.Equal(t, "id IN (?)", q)
 		assert.Equal(t, []interface{}{MyValuer{}}, args)
 	})
 
 	t.Run("More arguments", func(t *testing.T) {
-		_, _, err := in("id IN (?), id2 = ?", []string{"1", "2"})
+		_, _, err := ExpandIn("id IN (?), id2 = ?", []string{"1", "2"})
 		assert.EqualError(t, err, "number of bindVars exceeds arguments")
 	})
 
 	t.Run("Less arguments", func(t *testing.T) {
 		s := "2"
 		sPtr := &s
-		_, _, err := in("id = ?", []string{"1", "2"}, sPtr)
+		_, _, err := ExpandIn("id = ?", []string{"1", "2"}, sPtr)
 		assert.EqualError(t, err, "number of bindVars less than number arguments")
 	})
 
 	t.Run("No slice", func(t *testing.T) {
-		_, _, err := in("id IN (?)", "1")
+		_, _, err := ExpandIn("id IN (?)", "1")
 		assert.NoError(t, err)
 	})
 
 	t.Run("Empty slice", func(t *testing.T) {
-		_, _, err := in("id IN (?)", []string{})
+		_, _, err := ExpandIn("id IN (?)", []string{})
 		assert.Error(t, err, "empty slice passed to 'in' query")
 	})
 
 	t.Run("Skip not slice", func(t *testing.T) {
-		_, _, err := in("id IN (?), id2 = ?", "1", []interface{}{"2"})
+		_, _, err := ExpandIn("id IN (?), id2 = ?", "1", []interface{}{"2"})
 		assert.NoError(t, err)
 	})
 }
@@ -2,6 +2,8 @@ package rqp
 
 import (
 	"fmt"
+	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 )
@@ -22,6 +24,96 @@ type Filter struct {
 	Method Method // compare method, takes from Key (eg. EQ)
 	Value  interface{}
 	OR     StateOR
+
+	sql string // SQL of subquery for internal methods
+}
+
+// IsRaw returns true if filter is a raw SQL condition added by AddFilterRaw
+func (f *Filter) IsRaw() bool {
+	return f.Method == raw
+}
+
+// IsOR returns true if filter is a part of OR statement
+func (f *Filter) IsOR() bool {
+	return f.OR != NoOR
+}
+
+// IsStartOR returns true if filter opens OR statement
+func (f *Filter) IsStartOR() bool {
+	return f.OR == StartOR
+}
+
+// IsInOR returns true if filter is in the middle of OR statement
+func (f *Filter) IsInOR() bool {
+	return f.OR == InOR
+}
+
+// IsEndOR returns true if filter closes OR statement
+func (f *Filter) IsEndOR() bool {
+	return f.OR == EndOR
+}
+
+// Clone makes copy of Filter, slice values are copied too
+func (f *Filter) Clone() *Filter {
+	fNew := *f
+
+	if v := reflect.ValueOf(f.Value); v.Kind() == reflect.Slice && !v.IsNil() {
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		fNew.Value = c.Interface()
+	}
+
+	return &fNew
+}
+
+// Equals returns true if filters have the same name, method, value and OR state.
+// Key isn't compared because it's only a source of name and method.
+func (f *Filter) Equals(other *Filter) bool {
+	if other == nil {
+		return false
+	}
+	return f.OR == other.OR && f.sameCondition(other)
+}
+
+// sameCondition returns true if filters have the same name, method and value
+func (f *Filter) sameCondition(other *Filter) bool {
+	return f.Name == other.Name &&
+		f.Method == other.Method &&
+		f.sql == other.sql &&
+		reflect.DeepEqual(f.Value, other.Value)
+}
+
+// ToQueryParam returns filter in form of URL query parameter (eg. "id[gte]=5").
+// Values of IN/NIN are joined by comma (","). Filter of OR statement is returned
+// alone without other filters of the statement.
+// Raw and subquery filters have no URL form, empty string is returned for them.
+func (f *Filter) ToQueryParam() string {
+	return f.queryParam(",")
+}
+
+// queryParam returns filter in form of URL query parameter with values joined by delimiter
+func (f *Filter) queryParam(delimiter string) string {
+	if _, ok := translateMethods[f.Method]; !ok {
+		return ""
+	}
+
+	var values []string
+	if v := reflect.ValueOf(f.Value); v.Kind() == reflect.Slice {
+		values = make([]string, v.Len())
+		for i := range values {
+			values[i] = escapeQueryValue(fmt.Sprint(v.Index(i).Interface()))
+		}
+	} else {
+		values = []string{escapeQueryValue(fmt.Sprint(f.Value))}
+	}
+
+	return fmt.Sprintf("%s[%s]=%s", url.QueryEscape(f.Name), strings.ToLower(string(f.Method)), strings.Join(values, delimiter))
+}
+
+// escapeQueryValue escapes value for query part of URL
+// keeping wildcards ("*") of LIKE filters readable
+func escapeQueryValue(value string) string {
+	return strings.Replace(url.QueryEscape(value), "%2A", "*", -1)
 }
 
 // detectValidation
@@ -65,6 +157,16 @@ func detectType(name string, validations Validations) string {
 	return "string"
 }
 
+// isLikeMethod returns true if method compares by pattern
+func isLikeMethod(m Method) bool {
+	return m == LIKE || m == ILIKE || m == NLIKE || m == NILIKE
+}
+
+// isNullFilter returns true if filter compares to NULL (IS NULL or IS NOT NULL)
+func isNullFilter(f *Filter) bool {
+	return (f.Method == IS || f.Method == NOT) && f.Value == NULL
+}
+
 func isNotNull(f *Filter) bool {
 	s, ok := f.Value.(string)
 	if !ok {
@@ -75,6 +177,7 @@ func isNotNull(f *Filter) bool {
 
 // rawKey - url key
 // value - must be one value (if need IN method then values must be separated by comma (,))
+// Value isn't validated here, see Query.validate
 func newFilter(rawKey string, value string, delimiter string, validations Validations) (*Filter, error) {
 	f := &Filter{
 		Key: rawKey,
@@ -86,8 +189,7 @@ func newFilter(rawKey string, value string, delimiter string, validations Valida
 	}
 
 	// detect have we validator func definition on this parameter or not
-	validate, ok := detectValidation(f.Name, validations)
-	if !ok {
+	if _, ok := detectValidation(f.Name, validations); !ok {
 		return nil, ErrValidationNotFound
 	}
 
@@ -98,12 +200,6 @@ func newFilter(rawKey string, value string, delimiter string, validations Valida
 		return nil, err
 	}
 
-	if !isNotNull(f) && validate != nil {
-		if err := f.validate(validate); err != nil {
-			return nil, err
-		}
-	}
-
 	return f, nil
 }
 
@@ -169,7 +265,9 @@ func (f *Filter) parseValue(valueType string, value string, delimiter string) er
 
 	var list []string
 
-	if strings.Contains(value, delimiter) && (f.Method == IN || f.Method == NIN) {
+	// LIKE patterns are free text and could contain delimiter,
+	// lists for other methods than IN and NIN are rejected by setters
+	if strings.Contains(value, delimiter) && !isLikeMethod(f.Method) {
 		list = strings.Split(value, delimiter)
 	} else {
 		list = append(list, value)
@@ -212,10 +310,16 @@ func (f *Filter) Where() (string, error) {
 		return exp, ErrUnknownMethod
 	case IN, NIN:
 		exp = fmt.Sprintf("%s %s (?)", f.Name, translateMethods[f.Method])
-		exp, _, _ = in(exp, f.Value)
+		exp, _, _ = ExpandIn(exp, f.Value)
 		return exp, nil
 	case raw:
+		if err := f.checkRawArgs(); err != nil {
+			return exp, err
+		}
 		return f.Name, nil
+	case inSubquery:
+		exp = fmt.Sprintf("%s IN (%s)", f.Name, f.sql)
+		return exp, nil
 	default:
 		return exp, ErrUnknownMethod
 	}
@@ -247,16 +351,36 @@ func (f *Filter) Args() ([]interface{}, error) {
 		args = append(args, value)
 		return args, nil
 	case IN, NIN:
-		_, params, _ := in("?", f.Value)
+		_, params, _ := ExpandIn("?", f.Value)
 		args = append(args, params...)
 		return args, nil
 	case raw:
+		if err := f.checkRawArgs(); err != nil {
+			return nil, err
+		}
+		if v, ok := f.Value.([]interface{}); ok {
+			args = append(args, v...)
+		}
+		return args, nil
+	case inSubquery:
+		if v, ok := f.Value.([]interface{}); ok {
+			args = append(args, v...)
+		}
 		return args, nil
 	default:
 		return nil, ErrUnknownMethod
 	}
 }
 
+// checkRawArgs checks that number of placeholders in raw condition
+// is equal to number of its arguments if there are any
+func (f *Filter) checkRawArgs() error {
+	if v, ok := f.Value.([]interface{}); ok && strings.Count(f.Name, "?") != len(v) {
+		return ErrBadFormat
+	}
+	return nil
+}
+
 func (f *Filter) setInt(list []string) error {
 	if len(list) == 1 {
 		switch f.Method {
@@ -100,3 +100,100 @@ func Test_RemoveOrEntries(t *testing.T) {
 		})
 	}
 }
+
+func TestFilter_Is(t *testing.T) {
+	q := New().AddFilterRaw("deleted_at IS NULL")
+	q.AddORFilters(func(query *Query) {
+		query.AddFilter("firstname", ILIKE, "*hello*")
+		query.AddFilter("middlename", ILIKE, "*hello*")
+		query.AddFilter("lastname", ILIKE, "*hello*")
+	})
+
+	assert.True(t, q.Filters[0].IsRaw())
+	assert.False(t, q.Filters[0].IsOR())
+
+	assert.False(t, q.Filters[1].IsRaw())
+	assert.True(t, q.Filters[1].IsOR())
+	assert.True(t, q.Filters[1].IsStartOR())
+	assert.True(t, q.Filters[2].IsInOR())
+	assert.True(t, q.Filters[3].IsEndOR())
+	assert.False(t, q.Filters[3].IsStartOR())
+}
+
+func TestFilter_Clone(t *testing.T) {
+	f := &Filter{Key: "id[in]", Name: "id", Method: IN, Value: []string{"1", "2"}, OR: StartOR}
+	c := f.Clone()
+	assert.Equal(t, f, c)
+	assert.False(t, f == c)
+
+	c.Value.([]string)[0] = "3"
+	assert.Equal(t, []string{"1", "2"}, f.Value)
+
+	f = &Filter{Name: "id", Method: EQ, Value: 1}
+	assert.Equal(t, f, f.Clone())
+
+	f = &Filter{Name: "id", Method: IN, Value: []int(nil)}
+	assert.Equal(t, f, f.Clone())
+}
+
+func TestFilter_Equals(t *testing.T) {
+	f := &Filter{Key: "id[in]", Name: "id", Method: IN, Value: []int{1, 2}}
+
+	assert.True(t, f.Equals(&Filter{Name: "id", Method: IN, Value: []int{1, 2}}))
+	assert.False(t, f.Equals(&Filter{Name: "id", Method: IN, Value: []int{1, 3}}))
+	assert.False(t, f.Equals(&Filter{Name: "id", Method: IN, Value: []string{"1", "2"}}))
+	assert.False(t, f.Equals(&Filter{Name: "id", Method: NIN, Value: []int{1, 2}}))
+	assert.False(t, f.Equals(&Filter{Name: "id", Method: IN, Value: []int{1, 2}, OR: StartOR}))
+	assert.False(t, f.Equals(nil))
+
+	f = &Filter{Name: "id", Method: EQ, Value: 1}
+	assert.True(t, f.Equals(&Filter{Key: "id[eq]", Name: "id", Method: EQ, Value: 1}))
+	assert.False(t, f.Equals(&Filter{Name: "id", Method: EQ, Value: "1"}))
+	assert.False(t, f.Equals(&Filter{Name: "uid", Method: EQ, Value: 1}))
+}
+
+func TestFilter_ToQueryParam(t *testing.T) {
+	cases := []struct {
+		filter *Filter
+		want   string
+	}{
+		{&Filter{Name: "id", Method: GTE, Value: 5}, "id[gte]=5"},
+		{&Filter{Name: "id", Method: EQ, Value: "a&b=c"}, "id[eq]=a%26b%3Dc"},
+		{&Filter{Name: "id", Method: IN, Value: []int{1, 2}}, "id[in]=1,2"},
+		{&Filter{Name: "s", Method: NIN, Value: []string{"a b", "c"}}, "s[nin]=a+b,c"},
+		{&Filter{Name: "id", Method: IS, Value: NULL}, "id[is]=NULL"},
+		{&Filter{Name: "active", Method: EQ, Value: true}, "active[eq]=true"},
+		{&Filter{Name: "email", Method: LIKE, Value: "*tim*", OR: StartOR}, "email[like]=*tim*"},
+		{&Filter{Name: "id > 1", Method: raw}, ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.filter.ToQueryParam())
+	}
+
+	assert.Equal(t, "id[in]=1;2", (&Filter{Name: "id", Method: IN, Value: []int{1, 2}}).queryParam(";"))
+}
+
+func TestFilter_Raw(t *testing.T) {
+	f := &Filter{Name: "a > ? AND b < ?", Method: raw, Value: []interface{}{1, 2}}
+	where, err := f.Where()
+	assert.NoError(t, err)
+	assert.Equal(t, "a > ? AND b < ?", where)
+	args, err := f.Args()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2}, args)
+
+	f = &Filter{Name: "a > ?", Method: raw, Value: []interface{}{1, 2}}
+	_, err = f.Where()
+	assert.Equal(t, ErrBadFormat, err)
+	_, err = f.Args()
+	assert.Equal(t, ErrBadFormat, err)
+
+	// without args condition is used as is
+	f = &Filter{Name: "data ? 'key'", Method: raw}
+	where, err = f.Where()
+	assert.NoError(t, err)
+	assert.Equal(t, "data ? 'key'", where)
+	args, err = f.Args()
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+}
@@ -0,0 +1,77 @@
+package rqp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// As returns copy of the Query with parsed query of URL of http.Request.
+// The Query is used as a template and isn't modified, so it's safe to share it between requests.
+func (q *Query) As(r *http.Request) (*Query, error) {
+	qNew := q.Clone()
+	return qNew, qNew.ParseValues(r.URL.Query())
+}
+
+// AsHandler returns http.HandlerFunc which parses query of URL of every request
+// by a copy of the Query and calls next with it. The Query is used as a template
+// and isn't modified, so it's safe to share it between requests.
+// If parsing fails it responds with status 400 and JSON body `{"error": "..."}`.
+//
+// Example:
+//
+//	http.Handle("/users", q.AsHandler(func(w http.ResponseWriter, r *http.Request, q *rqp.Query) {
+//	    rows, err := db.Query(q.SQL("users"), q.Args()...)
+//	    ...
+//	}))
+func (q *Query) AsHandler(next func(w http.ResponseWriter, r *http.Request, q *Query)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		qNew, err := q.As(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		next(w, r, qNew)
+	}
+}
+
+// contextKey is a type of keys of context values of the package
+type contextKey string
+
+// QueryKey is a key of context value which contains Query parsed by AsMiddleware
+const QueryKey contextKey = "rqp.Query"
+
+// AsMiddleware returns http.Handler which parses query of URL of every request
+// by a copy of the Query like AsHandler does, stores it in context of request
+// by QueryKey and calls next. Use FromContext to get the Query in next handlers.
+func (q *Query) AsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qNew, err := q.As(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), QueryKey, qNew)))
+	})
+}
+
+// NewMiddleware returns middleware which parses query of URL of every request
+// with validations, compatible with chi, gorilla/mux etc., see AsMiddleware
+func NewMiddleware(v Validations) func(http.Handler) http.Handler {
+	return New().SetValidations(v).AsMiddleware
+}
+
+// FromContext returns Query stored in context by AsMiddleware
+func FromContext(ctx context.Context) (*Query, bool) {
+	q, ok := ctx.Value(QueryKey).(*Query)
+	return q, ok
+}
+
+// writeJSONError responds with status and JSON body `{"error": "..."}`
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,87 @@
+package rqp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_As(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil}).AddFilter("deleted", EQ, false)
+
+	q1, err := q.As(httptest.NewRequest(http.MethodGet, "/users?id=1", nil))
+	assert.NoError(t, err)
+	q2, err := q.As(httptest.NewRequest(http.MethodGet, "/users?id[in]=2,3", nil))
+	assert.NoError(t, err)
+
+	assert.Equal(t, " WHERE id = ?", q1.WHERE())
+	assert.Equal(t, " WHERE id IN (?, ?)", q2.WHERE())
+	assert.Equal(t, " WHERE deleted = ?", q.WHERE())
+
+	_, err = q.As(httptest.NewRequest(http.MethodGet, "/users?id=one", nil))
+	assert.Error(t, err)
+}
+
+func TestQuery_AsHandler(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil})
+
+	var got *Query
+	handler := q.AsHandler(func(w http.ResponseWriter, r *http.Request, q *Query) {
+		got = q
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/users?id=1", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, " WHERE id = ?", got.WHERE())
+	assert.Equal(t, []interface{}{1}, got.Args())
+	// template isn't modified
+	assert.Len(t, q.Filters, 0)
+
+	got = nil
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/users?id=one", nil))
+	assert.Nil(t, got)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": "id: bad format"}`, w.Body.String())
+}
+
+func TestQuery_AsMiddleware(t *testing.T) {
+	var (
+		got *Query
+		ok  bool
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok = FromContext(r.Context())
+	})
+
+	q := New().SetValidations(Validations{"id:int": nil})
+	handler := q.AsMiddleware(next)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?id=1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, ok)
+	assert.Equal(t, " WHERE id = ?", got.WHERE())
+	assert.Len(t, q.Filters, 0)
+
+	got, ok = nil, false
+	w = httptest.NewRecorder()
+	NewMiddleware(Validations{"id:int": nil})(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?id=one", nil))
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "id: bad format"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	NewMiddleware(Validations{"id:int": nil})(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?id=2", nil))
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{2}, got.Args())
+
+	_, ok = FromContext(context.Background())
+	assert.False(t, ok)
+}
@@ -0,0 +1,131 @@
+//go:build integration
+// +build integration
+
+// Package integration runs SQL statements built by rqp against a real SQLite database.
+// It's a separate module to keep the database driver out of dependencies of rqp.
+//
+// Run: cd integration && go test -tags integration ./...
+package integration
+
+import (
+	"database/sql"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rqp "github.com/timsolov/rest-query-parser"
+	_ "modernc.org/sqlite"
+)
+
+func setupDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE users (
+		id         INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		email      TEXT NOT NULL,
+		age        INTEGER NOT NULL,
+		active     BOOLEAN NOT NULL,
+		deleted_at TEXT
+	)`)
+	require.NoError(t, err)
+
+	users := []struct {
+		id        int
+		name      string
+		email     string
+		age       int
+		active    bool
+		deletedAt interface{}
+	}{
+		{1, "tim", "tim@example.com", 30, true, nil},
+		{2, "tom", "tom@example.com", 25, false, nil},
+		{3, "ann", "ann@mail.org", 41, true, "2020-10-02"},
+		{4, "timothy", "timothy@mail.org", 19, true, nil},
+		{5, "bob", "bob@example.com", 35, false, "2021-01-01"},
+	}
+	for _, u := range users {
+		_, err = db.Exec("INSERT INTO users (id, name, email, age, active, deleted_at) VALUES (?, ?, ?, ?, ?, ?)",
+			u.id, u.name, u.email, u.age, u.active, u.deletedAt)
+		require.NoError(t, err)
+	}
+
+	return db
+}
+
+func TestSQLite(t *testing.T) {
+	db := setupDB(t)
+
+	validations := rqp.Validations{
+		"fields":      rqp.In("id", "name", "email"),
+		"sort":        rqp.In("id", "name", "age"),
+		"id:int":      nil,
+		"age:int":     nil,
+		"active:bool": nil,
+		"name":        nil,
+		"email":       nil,
+		"deleted_at":  nil,
+	}
+
+	cases := []struct {
+		name  string
+		query string
+		ids   []int
+	}{
+		{name: "EQ", query: "id=2", ids: []int{2}},
+		{name: "NE", query: "id[ne]=2&sort=id", ids: []int{1, 3, 4, 5}},
+		{name: "GT LTE", query: "age[gt]=20&age[lte]=35&sort=id", ids: []int{1, 2, 5}},
+		{name: "bool", query: "active=true&sort=id", ids: []int{1, 3, 4}},
+		{name: "LIKE", query: "name[like]=tim*&sort=id", ids: []int{1, 4}},
+		{name: "NLIKE", query: "email[nlike]=*example.com&sort=id", ids: []int{3, 4}},
+		{name: "IN", query: "id[in]=1,3,5&sort=id", ids: []int{1, 3, 5}},
+		{name: "NIN", query: "name[nin]=tim,tom&sort=id", ids: []int{3, 4, 5}},
+		{name: "IS NULL", query: "deleted_at[is]=null&sort=id", ids: []int{1, 2, 4}},
+		{name: "IS NOT NULL", query: "deleted_at[not]=null&sort=id", ids: []int{3, 5}},
+		{name: "sort", query: "sort=-age", ids: []int{3, 5, 1, 2, 4}},
+		{name: "sort by two fields", query: "active=true&sort=-name,id", ids: []int{4, 1, 3}},
+		{name: "pagination", query: "sort=id&limit=2&offset=1", ids: []int{2, 3}},
+		{name: "OR", query: "name[eq]=ann|age[lt]=20&sort=id", ids: []int{3, 4}},
+		{name: "OR with AND", query: "name[like]=t*|email[like]=ann*&active=true&sort=id", ids: []int{1, 3, 4}},
+		{name: "fields", query: "fields=id,name&id[gte]=4&sort=id", ids: []int{4, 5}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			URL, err := url.Parse("/?" + c.query)
+			require.NoError(t, err)
+
+			q, err := rqp.NewParse(URL.Query(), validations)
+			require.NoError(t, err)
+			// id goes first, other selected fields are scanned but ignored
+			if len(q.Fields) == 0 {
+				q.AddField("id")
+			}
+
+			rows, err := db.Query(q.SQL("users"), q.Args()...)
+			require.NoError(t, err, q.SQL("users"))
+			defer rows.Close()
+
+			cols, err := rows.Columns()
+			require.NoError(t, err)
+
+			var ids []int
+			for rows.Next() {
+				dest := make([]interface{}, len(cols))
+				var id int
+				dest[0] = &id
+				for i := 1; i < len(cols); i++ {
+					dest[i] = new(interface{})
+				}
+				require.NoError(t, rows.Scan(dest...))
+				ids = append(ids, id)
+			}
+			require.NoError(t, rows.Err())
+
+			assert.Equal(t, c.ids, ids, q.SQL("users"))
+		})
+	}
+}
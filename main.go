@@ -2,9 +2,13 @@ package rqp
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -20,9 +24,26 @@ type Query struct {
 	Sorts   []Sort
 	Filters []*Filter
 
-	delimiterIN   string
-	delimiterOR   string
-	ignoreUnknown bool
+	delimiterIN            string
+	delimiterOR            string
+	ignoreUnknown          bool
+	specialFilters         map[string]bool
+	keyPattern             *regexp.Regexp
+	keyPatternErr          error // set by SetKeyPattern if pattern can't be compiled
+	namedFilters           map[string]*Filter
+	multiValueMode         MultiValueMode
+	arrayParamStyle        ArrayParamStyle
+	availableFields        []string
+	allowNullSort          bool
+	requireFilterForDelete bool
+	requireFilters         bool
+	ctes                   []cte
+	windows                []window
+	lock                   string
+	selectAs               string
+	nullAsArg              bool
+	onAfterParse           func(q *Query, err error)
+	logParse               func(q *Query, err error, took time.Duration) // set by SetLogger
 
 	Error error
 }
@@ -47,11 +68,16 @@ var (
 	IN     Method = "IN"
 	NIN    Method = "NIN"
 	raw    Method = "raw" // internal usage
+
+	inSubquery Method = "inSubquery" // internal usage
 )
 
 // NULL constant
 const NULL = "NULL"
 
+// defaultKeyPattern is a pattern for keys of filters, see SetKeyPattern
+var defaultKeyPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]*(\[.*\])?$`)
+
 var (
 	translateMethods map[Method]string = map[Method]string{
 		EQ:     "=",
@@ -71,10 +97,51 @@ var (
 	}
 )
 
+// MultiValueMode defines how repeated parameters of URL (eg. `?id=1&id=2`) are parsed
+type MultiValueMode byte
+
+// Multi value modes:
+const (
+	// MultiValueAnd parses every value as a separate filter joined by AND (default)
+	MultiValueAnd MultiValueMode = iota
+	// MultiValueIN joins values into one IN (NOT IN for `ne`) filter
+	MultiValueIN
+	// MultiValueFirst parses only the first value
+	MultiValueFirst
+)
+
+// ArrayParamStyle defines how array parameters of URL are recognized
+type ArrayParamStyle byte
+
+// Array parameter styles:
+const (
+	// ArrayParamStyleRepeat treats repeated parameter `?id=1&id=2` as array (default), see SetMultiValueMode
+	ArrayParamStyleRepeat ArrayParamStyle = iota
+	// ArrayParamStyleBracket parses PHP style array `?id[]=1&id[]=2` as `?id[in]=1,2`
+	ArrayParamStyleBracket
+)
+
+// cte is a named common table expression
+type cte struct {
+	name string
+	sql  string
+	args []interface{}
+}
+
+// window is a named window definition
+type window struct {
+	name        string
+	partitionBy string
+	orderBy     string
+}
+
 // Sort is ordering struct
 type Sort struct {
 	By   string
 	Desc bool
+	// SortPriority defines position of the Sort in ORDER BY statement,
+	// sorts with lower priority go first. Sorts from URL have priority 0.
+	SortPriority int
 }
 
 // IgnoreUnknownFilters set behavior for Parser to raise ErrFilterNotAllowed to undefined filters or not
@@ -83,6 +150,77 @@ func (q *Query) IgnoreUnknownFilters(i bool) *Query {
 	return q
 }
 
+// AllowSpecialFilters allows filters which have no validation.
+// Values of these filters are parsed as strings and are not validated.
+func (q *Query) AllowSpecialFilters(fields ...string) *Query {
+	if q.specialFilters == nil {
+		q.specialFilters = make(map[string]bool)
+	}
+	for _, field := range fields {
+		q.specialFilters[field] = true
+	}
+	return q
+}
+
+// SetRequireFilters sets behavior for Parser to return ErrRequired
+// if URL contains no filters, e.g. for search which needs at least one term
+func (q *Query) SetRequireFilters(require bool) *Query {
+	q.requireFilters = require
+	return q
+}
+
+// SetAvailableFields sets list of all fields which are used for SELECT statement
+// when "fields" contains only excluded fields prefixed by minus ("-").
+// E.g. `?fields=-password` selects all available fields except "password".
+func (q *Query) SetAvailableFields(fields ...string) *Query {
+	q.availableFields = fields
+	return q
+}
+
+// SetAllowNullSort allows to reset sorting by `?sort=null`.
+// It is disabled by default because "null" could be a name of field.
+func (q *Query) SetAllowNullSort(allow bool) *Query {
+	q.allowNullSort = allow
+	return q
+}
+
+// SetNullAsArg sets behavior for IS/NOT NULL filters to use placeholder instead of NULL literal:
+// WHERE gets `field IS ?` and Args gets string "NULL" for them.
+// It's useful for drivers and ORMs which expect all values as arguments.
+func (q *Query) SetNullAsArg(b bool) *Query {
+	q.nullAsArg = b
+	return q
+}
+
+// SetMultiValueMode sets behavior for Parser to handle repeated parameters of URL
+func (q *Query) SetMultiValueMode(mode MultiValueMode) *Query {
+	q.multiValueMode = mode
+	return q
+}
+
+// SetArrayParamStyle sets style of array parameters of URL.
+// By default it's ArrayParamStyleRepeat.
+func (q *Query) SetArrayParamStyle(style ArrayParamStyle) *Query {
+	q.arrayParamStyle = style
+	return q
+}
+
+// SetKeyPattern sets regular expression which keys of filters in URL must match,
+// otherwise Parse raises ErrInvalidCharacter. The default pattern allows letters,
+// digits, underscore and dot with optional method in brackets: ^[a-zA-Z_][a-zA-Z0-9_.]*(\[.*\])?$
+// If pattern can't be compiled q.Error is set and Parse returns the error.
+func (q *Query) SetKeyPattern(pattern string) *Query {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		q.keyPatternErr = errors.Wrap(ErrBadFormat, err.Error())
+		q.Error = q.keyPatternErr
+		return q
+	}
+	q.keyPattern = re
+	q.keyPatternErr = nil
+	return q
+}
+
 // SetDelimiterIN sets delimiter for values of filters
 func (q *Query) SetDelimiterIN(d string) *Query {
 	q.delimiterIN = d
@@ -141,6 +279,33 @@ func (q *Query) SELECT() string {
 	return fmt.Sprintf("SELECT %s", q.FieldsString())
 }
 
+// SelectAs returns list of columns with aliases for SELECT statement.
+// Keys of fields are SQL expressions and values are aliases, columns are ordered alphabetically.
+// Expression with empty alias goes without AS.
+//
+// Return example: `u.email AS user_email, u.name AS user_name`
+func (q *Query) SelectAs(fields map[string]string) string {
+	cols := make([]string, 0, len(fields))
+	for col := range fields {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	for i, col := range cols {
+		if alias := fields[col]; alias != "" {
+			cols[i] = col + " AS " + alias
+		}
+	}
+	return strings.Join(cols, ", ")
+}
+
+// SetSelectAs sets columns with aliases which are used by SQL and SQLWithAlias
+// instead of fields of Query, see SelectAs. Empty or nil fields resets it.
+func (q *Query) SetSelectAs(fields map[string]string) *Query {
+	q.selectAs = q.SelectAs(fields)
+	return q
+}
+
 // HaveField returns true if request asks for specified field
 func (q *Query) HaveField(field string) bool {
 	return stringInSlice(field, q.Fields)
@@ -152,6 +317,51 @@ func (q *Query) AddField(field string) *Query {
 	return q
 }
 
+// AppendField adds fields to the end of SELECT statement
+func (q *Query) AppendField(fields ...string) *Query {
+	q.Fields = append(q.Fields, fields...)
+	return q
+}
+
+// PrependField adds fields to the beginning of SELECT statement
+func (q *Query) PrependField(fields ...string) *Query {
+	q.Fields = append(append(make([]string, 0, len(fields)+len(q.Fields)), fields...), q.Fields...)
+	return q
+}
+
+// RemoveField removes the first occurrence of field from SELECT statement
+func (q *Query) RemoveField(name string) error {
+	for i, v := range q.Fields {
+		if v == name {
+			q.Fields = append(q.Fields[:i], q.Fields[i+1:]...)
+			return nil
+		}
+	}
+	return ErrFilterNotFound
+}
+
+// RemoveAllFields removes all fields from SELECT statement
+func (q *Query) RemoveAllFields() *Query {
+	q.Fields = nil
+	return q
+}
+
+// ReplaceField replaces field in SELECT statement with new name.
+// Unlike ReplaceNames it doesn't touch filters and sorts.
+func (q *Query) ReplaceField(old, new string) error {
+	var found bool
+	for i, v := range q.Fields {
+		if v == old {
+			q.Fields[i] = new
+			found = true
+		}
+	}
+	if !found {
+		return ErrFilterNotFound
+	}
+	return nil
+}
+
 // OFFSET returns word OFFSET with number
 //
 // Return example: ` OFFSET 0`
@@ -221,6 +431,45 @@ func (q *Query) HaveSortBy(by string) bool {
 	return false
 }
 
+// SortBy returns sort by specified field name and true if it's found
+func (q *Query) SortBy(by string) (Sort, bool) {
+	s, _, ok := q.SortByIndex(by)
+	return s, ok
+}
+
+// SortByIndex returns sort by specified field name, its index in Sorts and true if it's found
+func (q *Query) SortByIndex(by string) (Sort, int, bool) {
+
+	for i, v := range q.Sorts {
+		if v.By == by {
+			return v, i, true
+		}
+	}
+
+	return Sort{}, -1, false
+}
+
+// ReplaceSort replaces the first sort by specified field name with newSort
+func (q *Query) ReplaceSort(by string, newSort Sort) error {
+	_, i, ok := q.SortByIndex(by)
+	if !ok {
+		return ErrFilterNotFound
+	}
+	q.Sorts[i] = newSort
+	q.prioritizeSorts()
+	return nil
+}
+
+// ReplaceSortDesc changes direction of the first sort by specified field name
+func (q *Query) ReplaceSortDesc(by string, desc bool) error {
+	_, i, ok := q.SortByIndex(by)
+	if !ok {
+		return ErrFilterNotFound
+	}
+	q.Sorts[i].Desc = desc
+	return nil
+}
+
 // AddSortBy adds an ordering rule to Query
 func (q *Query) AddSortBy(by string, desc bool) *Query {
 	q.Sorts = append(q.Sorts, Sort{
@@ -230,6 +479,27 @@ func (q *Query) AddSortBy(by string, desc bool) *Query {
 	return q
 }
 
+// AddSortByWeighted adds an ordering rule with priority to Query.
+// Sorts with lower priority go first in ORDER BY statement,
+// sorts with equal priority keep the order of addition.
+// E.g. use positive priority to add tie-breaker which always goes last.
+func (q *Query) AddSortByWeighted(by string, desc bool, priority int) *Query {
+	q.Sorts = append(q.Sorts, Sort{
+		By:           by,
+		Desc:         desc,
+		SortPriority: priority,
+	})
+	q.prioritizeSorts()
+	return q
+}
+
+// prioritizeSorts orders sorts by their priority keeping the order of sorts with equal priority
+func (q *Query) prioritizeSorts() {
+	sort.SliceStable(q.Sorts, func(i, j int) bool {
+		return q.Sorts[i].SortPriority < q.Sorts[j].SortPriority
+	})
+}
+
 // HaveFilter returns true if request contains some filter
 func (q *Query) HaveFilter(name string) bool {
 
@@ -284,64 +554,242 @@ func (q *Query) AddORFilters(fn func(query *Query)) *Query {
 // AddFilterRaw adds a filter to Query as SQL condition.
 // This function supports only single condition per one call.
 // If you'd like add more then one conditions you should call this func several times.
-func (q *Query) AddFilterRaw(condition string) *Query {
-	q.Filters = append(q.Filters, &Filter{
+// Optional args are arguments for placeholders ("?") of condition, their number must be equal
+// to number of placeholders otherwise the filter isn't added and q.Error is set to ErrBadFormat.
+//
+// Example: q.AddFilterRaw("created_at > NOW() - ? * INTERVAL '1 day'", 7)
+func (q *Query) AddFilterRaw(condition string, args ...interface{}) *Query {
+	f := &Filter{
 		Name:   condition,
 		Method: raw,
+	}
+	if len(args) > 0 {
+		f.Value = args
+	}
+	if err := f.checkRawArgs(); err != nil {
+		q.Error = errors.Wrap(err, condition)
+		return q
+	}
+	q.Filters = append(q.Filters, f)
+	return q
+}
+
+// AddSubqueryFilter adds a filter `field IN (subquery)` to Query.
+// subArgs are arguments of subquery, they take place of the filter in Args().
+// Attention! subquery is used in SQL statement as is, never build it from user input.
+//
+// Example: q.AddSubqueryFilter("user_id", "SELECT id FROM users WHERE org_id = ?", []interface{}{orgID})
+func (q *Query) AddSubqueryFilter(field, subquery string, subArgs []interface{}) *Query {
+	q.Filters = append(q.Filters, &Filter{
+		Name:   field,
+		Method: inSubquery,
+		Value:  subArgs,
+		sql:    subquery,
 	})
 	return q
 }
 
+// NamedFilter adds the filter to Query and registers it under alias,
+// so it could be got by GetNamedFilter or removed by RemoveFilter with alias.
+func (q *Query) NamedFilter(alias string, f *Filter) *Query {
+	if q.namedFilters == nil {
+		q.namedFilters = make(map[string]*Filter)
+	}
+	q.namedFilters[alias] = f
+	q.Filters = append(q.Filters, f)
+	return q
+}
+
+// GetNamedFilter returns the filter registered under alias by NamedFilter
+// if it's still in Filters of Query
+func (q *Query) GetNamedFilter(alias string) (*Filter, error) {
+	if f, ok := q.namedFilters[alias]; ok && q.hasFilter(f) {
+		return f, nil
+	}
+	return nil, ErrFilterNotFound
+}
+
+// hasFilter returns true if f is in Filters of Query
+func (q *Query) hasFilter(f *Filter) bool {
+	for _, v := range q.Filters {
+		if v == f {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneNamedFilters removes aliases of filters which aren't in Filters of Query anymore
+func (q *Query) pruneNamedFilters() {
+	for alias, f := range q.namedFilters {
+		if !q.hasFilter(f) {
+			delete(q.namedFilters, alias)
+		}
+	}
+}
+
 // RemoveFilter removes the filter by name
+// or the filter registered under alias by NamedFilter
 func (q *Query) RemoveFilter(name string) error {
+	if f, ok := q.namedFilters[name]; ok {
+		delete(q.namedFilters, name)
+		for i := range q.Filters {
+			if q.Filters[i] == f {
+				q.removeFilterAt(i)
+				return nil
+			}
+		}
+		return ErrFilterNotFound
+	}
+
 	var found bool
 	for i := 0; i < len(q.Filters); i++ {
-		v := q.Filters[i]
+		if q.Filters[i].Name == name {
+			q.removeFilterAt(i)
+			found = true
+			i--
+		}
+	}
+	if !found {
+		return ErrFilterNotFound
+	}
+	q.pruneNamedFilters()
+	return nil
+}
 
-		// set next and previous Filter
-		var next, prev *Filter
-		if i+1 < len(q.Filters) {
-			next = q.Filters[i+1]
-		} else {
-			next = nil
+// UniqueFilters removes duplicates of filters with the same name, method and value
+// keeping the first occurrence. Filters are compared only with filters from the same
+// OR statement or with filters outside of OR statements.
+func (q *Query) UniqueFilters() *Query {
+	groups := q.filterGroups()
+
+	for i := 0; i < len(q.Filters); i++ {
+		for j := 0; j < i; j++ {
+			if groups[i] == groups[j] && q.Filters[i].sameCondition(q.Filters[j]) {
+				q.removeFilterAt(i)
+				// removal could turn OR statement into a plain filter
+				groups = q.filterGroups()
+				i--
+				break
+			}
 		}
-		if i-1 >= 0 {
-			prev = q.Filters[i-1]
-		} else {
-			prev = nil
+	}
+
+	return q
+}
+
+// filterGroups returns number of OR statement for every filter,
+// filters outside of OR statements are in group 0
+func (q *Query) filterGroups() []int {
+	groups := make([]int, len(q.Filters))
+	group := 0
+	for i, v := range q.Filters {
+		if v.OR == StartOR {
+			group++
+		}
+		if v.OR != NoOR {
+			groups[i] = group
 		}
+	}
+	return groups
+}
 
-		if v.Name == name {
-			// special cases for removing filters in OR statement
-			if v.OR == StartOR && next != nil {
-				if next.OR == EndOR {
-					next.OR = NoOR
-				} else {
-					next.OR = StartOR
-				}
-			} else if v.OR == EndOR && prev != nil {
-				if prev.OR == StartOR {
-					prev.OR = NoOR
-				} else {
-					prev.OR = EndOR
-				}
+// SortFilters reorders filters by less func keeping order of equal filters.
+// OR statements are moved as a whole and are ordered by their first filter,
+// filters inside of an OR statement are reordered too.
+// E.g. it could be used to put filters by indexed fields first.
+func (q *Query) SortFilters(less func(a, b *Filter) bool) *Query {
+	units := q.filterUnits()
+	for i := range units {
+		units[i] = append([]*Filter(nil), units[i]...)
+	}
+
+	// sort filters inside of OR statements
+	for _, unit := range units {
+		if len(unit) < 2 {
+			continue
+		}
+		sort.SliceStable(unit, func(i, j int) bool {
+			return less(unit[i], unit[j])
+		})
+		for i := range unit {
+			switch i {
+			case 0:
+				unit[i].OR = StartOR
+			case len(unit) - 1:
+				unit[i].OR = EndOR
+			default:
+				unit[i].OR = InOR
 			}
+		}
+	}
+
+	sort.SliceStable(units, func(i, j int) bool {
+		return less(units[i][0], units[j][0])
+	})
 
-			// safe remove element from slice
-			if i < len(q.Filters)-1 {
-				copy(q.Filters[i:], q.Filters[i+1:])
+	q.Filters = q.Filters[:0]
+	for _, unit := range units {
+		q.Filters = append(q.Filters, unit...)
+	}
+
+	return q
+}
+
+// filterUnits splits filters to units: single filter or whole OR statement.
+// Units are parts of q.Filters, copy them before reordering.
+func (q *Query) filterUnits() [][]*Filter {
+	var units [][]*Filter
+	for i := 0; i < len(q.Filters); i++ {
+		start := i
+		if q.Filters[i].OR == StartOR {
+			for i < len(q.Filters)-1 && q.Filters[i].OR != EndOR {
+				i++
 			}
-			q.Filters[len(q.Filters)-1] = nil
-			q.Filters = q.Filters[:len(q.Filters)-1]
+		}
+		units = append(units, q.Filters[start:i+1])
+	}
+	return units
+}
 
-			found = true
-			i--
+// removeFilterAt removes the filter by index and fixes OR statement around it
+func (q *Query) removeFilterAt(i int) {
+	v := q.Filters[i]
+
+	// set next and previous Filter
+	var next, prev *Filter
+	if i+1 < len(q.Filters) {
+		next = q.Filters[i+1]
+	} else {
+		next = nil
+	}
+	if i-1 >= 0 {
+		prev = q.Filters[i-1]
+	} else {
+		prev = nil
+	}
+
+	// special cases for removing filters in OR statement
+	if v.OR == StartOR && next != nil {
+		if next.OR == EndOR {
+			next.OR = NoOR
+		} else {
+			next.OR = StartOR
+		}
+	} else if v.OR == EndOR && prev != nil {
+		if prev.OR == StartOR {
+			prev.OR = NoOR
+		} else {
+			prev.OR = EndOR
 		}
 	}
-	if !found {
-		return ErrFilterNotFound
+
+	// safe remove element from slice
+	if i < len(q.Filters)-1 {
+		copy(q.Filters[i:], q.Filters[i+1:])
 	}
-	return nil
+	q.Filters[len(q.Filters)-1] = nil
+	q.Filters = q.Filters[:len(q.Filters)-1]
 }
 
 // AddValidation adds a validation to Query
@@ -385,15 +833,184 @@ func (q *Query) SetLimit(limit int) *Query {
 	return q
 }
 
+// Page returns number of page calculated from Offset and Limit, starts from 1.
+// Returns 1 if Limit isn't set.
+func (q *Query) Page() int {
+	if q.Limit <= 0 {
+		return 1
+	}
+	return q.Offset/q.Limit + 1
+}
+
+// PerPage returns number of elements per page which is Limit
+func (q *Query) PerPage() int {
+	if q.Limit <= 0 {
+		return 0
+	}
+	return q.Limit
+}
+
+// HasPagination returns true if Limit or Offset is set
+func (q *Query) HasPagination() bool {
+	return q.Limit > 0 || q.Offset > 0
+}
+
+// HasSorting returns true if Query contains any sort
+func (q *Query) HasSorting() bool {
+	return len(q.Sorts) > 0
+}
+
+// HasFilters returns true if Query contains any filter
+func (q *Query) HasFilters() bool {
+	return len(q.Filters) > 0
+}
+
+// Equals returns true if Query has the same fields, sorts, limit, offset and filters as other.
+// Filters are compared by name, method, value and OR state. Settings of Query like
+// validations and delimiters are ignored.
+func (q *Query) Equals(other *Query) bool {
+	if other == nil {
+		return false
+	}
+
+	if !equalStrings(q.Fields, other.Fields) ||
+		!equalSorts(q.Sorts, other.Sorts) ||
+		len(q.Filters) != len(other.Filters) ||
+		q.Limit != other.Limit ||
+		q.Offset != other.Offset {
+		return false
+	}
+
+	for i := range q.Filters {
+		if !q.Filters[i].Equals(other.Filters[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterChange is a filter which has got another value or OR state in Query.Diff
+type FilterChange struct {
+	Old *Filter
+	New *Filter
+}
+
+// QueryDiff represents differences between two queries, see Query.Diff
+type QueryDiff struct {
+	AddedFilters      []*Filter
+	RemovedFilters    []*Filter
+	ChangedFilters    []FilterChange
+	SortChanged       bool
+	PaginationChanged bool
+	FieldsChanged     bool
+}
+
+// Diff returns differences of other from the Query.
+// Filters are matched regardless of their order: equal filters are unchanged,
+// filters with the same name and method but another value or OR state are changed.
+func (q *Query) Diff(other *Query) QueryDiff {
+	if other == nil {
+		other = New()
+	}
+
+	diff := QueryDiff{
+		SortChanged:       !equalSorts(q.Sorts, other.Sorts),
+		PaginationChanged: q.Limit != other.Limit || q.Offset != other.Offset,
+		FieldsChanged:     !equalStrings(q.Fields, other.Fields),
+	}
+
+	oldFilters := append([]*Filter(nil), q.Filters...)
+	newFilters := append([]*Filter(nil), other.Filters...)
+
+	// drop equal filters
+	for i := 0; i < len(oldFilters); i++ {
+		for j := range newFilters {
+			if oldFilters[i].Equals(newFilters[j]) {
+				oldFilters = append(oldFilters[:i], oldFilters[i+1:]...)
+				newFilters = append(newFilters[:j], newFilters[j+1:]...)
+				i--
+				break
+			}
+		}
+	}
+
+	// match changed filters
+	for i := 0; i < len(oldFilters); i++ {
+		for j := range newFilters {
+			if oldFilters[i].Name == newFilters[j].Name && oldFilters[i].Method == newFilters[j].Method {
+				diff.ChangedFilters = append(diff.ChangedFilters, FilterChange{Old: oldFilters[i], New: newFilters[j]})
+				oldFilters = append(oldFilters[:i], oldFilters[i+1:]...)
+				newFilters = append(newFilters[:j], newFilters[j+1:]...)
+				i--
+				break
+			}
+		}
+	}
+
+	if len(oldFilters) > 0 {
+		diff.RemovedFilters = oldFilters
+	}
+	if len(newFilters) > 0 {
+		diff.AddedFilters = newFilters
+	}
+
+	return diff
+}
+
+// IsEmpty returns true if Query has no fields, sorts, filters, limit and offset
+func (q *Query) IsEmpty() bool {
+	return len(q.Fields) == 0 &&
+		len(q.Sorts) == 0 &&
+		len(q.Filters) == 0 &&
+		q.Limit == 0 &&
+		q.Offset == 0
+}
+
+// FilterCount returns number of filters
+func (q *Query) FilterCount() int {
+	return len(q.Filters)
+}
+
+// SortCount returns number of sorts
+func (q *Query) SortCount() int {
+	return len(q.Sorts)
+}
+
+// FieldCount returns number of fields
+func (q *Query) FieldCount() int {
+	return len(q.Fields)
+}
+
 // Clone makes copy of Query
 func (q *Query) Clone() *Query {
 	qNew := &Query{
-		Offset:        q.Offset,
-		Limit:         q.Limit,
-		delimiterIN:   q.delimiterIN,
-		delimiterOR:   q.delimiterOR,
-		ignoreUnknown: q.ignoreUnknown,
-		Error:         q.Error,
+		Offset:                 q.Offset,
+		Limit:                  q.Limit,
+		delimiterIN:            q.delimiterIN,
+		delimiterOR:            q.delimiterOR,
+		ignoreUnknown:          q.ignoreUnknown,
+		keyPattern:             q.keyPattern,
+		keyPatternErr:          q.keyPatternErr,
+		multiValueMode:         q.multiValueMode,
+		arrayParamStyle:        q.arrayParamStyle,
+		allowNullSort:          q.allowNullSort,
+		requireFilterForDelete: q.requireFilterForDelete,
+		requireFilters:         q.requireFilters,
+		lock:                   q.lock,
+		selectAs:               q.selectAs,
+		nullAsArg:              q.nullAsArg,
+		onAfterParse:           q.onAfterParse,
+		logParse:               q.logParse,
+		Error:                  q.Error,
+	}
+
+	// copy special filters
+	if q.specialFilters != nil {
+		qNew.specialFilters = make(map[string]bool)
+		for key := range q.specialFilters {
+			qNew.specialFilters[key] = q.specialFilters[key]
+		}
 	}
 
 	// copy query map
@@ -415,6 +1032,12 @@ func (q *Query) Clone() *Query {
 		}
 	}
 
+	// copy available fields
+	if q.availableFields != nil {
+		qNew.availableFields = make([]string, len(q.availableFields), cap(q.availableFields))
+		copy(qNew.availableFields, q.availableFields)
+	}
+
 	// copy Fields
 	if q.Fields != nil {
 		qNew.Fields = make([]string, len(q.Fields), cap(q.Fields))
@@ -426,14 +1049,205 @@ func (q *Query) Clone() *Query {
 		copy(qNew.Sorts, q.Sorts)
 	}
 	// copy Filters
+	cloned := make(map[*Filter]*Filter, len(q.Filters))
 	if q.Filters != nil {
 		qNew.Filters = make([]*Filter, len(q.Filters), cap(q.Filters))
-		copy(qNew.Filters, q.Filters)
+		for i, f := range q.Filters {
+			qNew.Filters[i] = f.Clone()
+			cloned[f] = qNew.Filters[i]
+		}
+	}
+	// copy named filters
+	if q.namedFilters != nil {
+		qNew.namedFilters = make(map[string]*Filter)
+		for key, f := range q.namedFilters {
+			if c, ok := cloned[f]; ok {
+				qNew.namedFilters[key] = c
+			} else {
+				qNew.namedFilters[key] = f.Clone()
+			}
+		}
+	}
+	// copy CTEs
+	if q.ctes != nil {
+		qNew.ctes = make([]cte, len(q.ctes), cap(q.ctes))
+		copy(qNew.ctes, q.ctes)
+	}
+	// copy windows
+	if q.windows != nil {
+		qNew.windows = make([]window, len(q.windows), cap(q.windows))
+		copy(qNew.windows, q.windows)
 	}
 
 	return qNew
 }
 
+// Apply calls modifiers one by one with the Query.
+// It allows to reuse prepared modifiers, e.g.:
+//   onlyActive := func(q *rqp.Query) { q.AddFilter("status", rqp.EQ, "active") }
+//   q.Apply(onlyActive)
+func (q *Query) Apply(modifiers ...func(*Query)) *Query {
+	for _, fn := range modifiers {
+		fn(q)
+	}
+	return q
+}
+
+// Tap calls fn with a copy of the Query for side effects like logging or metrics.
+// Changes made by fn don't affect the Query.
+func (q *Query) Tap(fn func(*Query)) *Query {
+	fn(q.Clone())
+	return q
+}
+
+// ToQueryString returns query part of URL which represents fields, sorts, limit, offset
+// and filters of the Query, e.g.: `fields=id,name&sort=-id&limit=10&id[gt]=1&email[like]=*tim*|name[like]=*tim*`
+// Filters of OR statement are joined by OR delimiter. Raw and subquery filters are omitted.
+// Parsing of the result with the same validations gives equal Query except order of filters
+// because parameters of URL are parsed in random order.
+func (q *Query) ToQueryString() string {
+	var parts []string
+
+	if len(q.Fields) > 0 {
+		fields := make([]string, len(q.Fields))
+		for i, field := range q.Fields {
+			fields[i] = escapeQueryValue(field)
+		}
+		parts = append(parts, "fields="+strings.Join(fields, q.delimiterIN))
+	}
+
+	if len(q.Sorts) > 0 {
+		sorts := make([]string, len(q.Sorts))
+		for i, s := range q.Sorts {
+			sorts[i] = escapeQueryValue(s.By)
+			if s.Desc {
+				sorts[i] = "-" + sorts[i]
+			}
+		}
+		parts = append(parts, "sort="+strings.Join(sorts, q.delimiterIN))
+	}
+
+	if q.Limit > 0 {
+		parts = append(parts, fmt.Sprintf("limit=%d", q.Limit))
+	}
+
+	if q.Offset > 0 {
+		parts = append(parts, fmt.Sprintf("offset=%d", q.Offset))
+	}
+
+	for _, unit := range q.filterUnits() {
+		var params []string
+		for _, f := range unit {
+			if param := f.queryParam(q.delimiterIN); param != "" {
+				params = append(params, param)
+			}
+		}
+		if len(params) > 0 {
+			parts = append(parts, strings.Join(params, q.delimiterOR))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+// Hash returns deterministic hash (SHA-256 in hex) of fields, sorts, limit, offset and filters of Query.
+// It could be used as a cache key. Order of filters joined by AND and order of filters
+// inside of OR statement don't change the hash.
+func (q *Query) Hash() string {
+	var sorts []string
+	for _, v := range q.Sorts {
+		if v.Desc {
+			sorts = append(sorts, "-"+v.By)
+		} else {
+			sorts = append(sorts, v.By)
+		}
+	}
+
+	return hashString(fmt.Sprintf(
+		"fields=%s;sort=%s;limit=%d;offset=%d;where=%s",
+		strings.Join(q.Fields, ","),
+		strings.Join(sorts, ","),
+		q.Limit,
+		q.Offset,
+		q.canonicalFilters(),
+	))
+}
+
+// HashFiltersOnly returns deterministic hash like Hash does but only of filters of Query
+func (q *Query) HashFiltersOnly() string {
+	return hashString("where=" + q.canonicalFilters())
+}
+
+// Fingerprint returns human-readable key of Query without values of filters, e.g.:
+//   fields=id,name;sort=-created_at;limit=20;where=id:EQ,(email:LIKE|name:LIKE)
+// Parts which aren't set are omitted. Filters are ordered by name like in Hash.
+// Raw filters are represented by word "raw" because they could contain values.
+func (q *Query) Fingerprint() string {
+	var parts []string
+
+	if len(q.Fields) > 0 {
+		parts = append(parts, "fields="+strings.Join(q.Fields, ","))
+	}
+	if len(q.Sorts) > 0 {
+		sorts := make([]string, len(q.Sorts))
+		for i, v := range q.Sorts {
+			if v.Desc {
+				sorts[i] = "-" + v.By
+			} else {
+				sorts[i] = v.By
+			}
+		}
+		parts = append(parts, "sort="+strings.Join(sorts, ","))
+	}
+	if q.Limit > 0 {
+		parts = append(parts, fmt.Sprintf("limit=%d", q.Limit))
+	}
+	if q.Offset > 0 {
+		parts = append(parts, fmt.Sprintf("offset=%d", q.Offset))
+	}
+
+	if units := q.filterUnits(); len(units) > 0 {
+		list := make([]string, len(units))
+		for i, unit := range units {
+			names := make([]string, len(unit))
+			for j, f := range unit {
+				if f.IsRaw() {
+					names[j] = "raw"
+				} else {
+					names[j] = fmt.Sprintf("%s:%s", f.Name, f.Method)
+				}
+			}
+			sort.Strings(names)
+			if len(names) > 1 {
+				list[i] = "(" + strings.Join(names, "|") + ")"
+			} else {
+				list[i] = names[0]
+			}
+		}
+		sort.Strings(list)
+		parts = append(parts, "where="+strings.Join(list, ","))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// canonicalFilters returns string representation of filters which doesn't depend on
+// order of filters joined by AND and order of filters inside of OR statement
+func (q *Query) canonicalFilters() string {
+	units := q.filterUnits()
+	list := make([]string, len(units))
+	for i, unit := range units {
+		parts := make([]string, len(unit))
+		for j, f := range unit {
+			parts[j] = fmt.Sprintf("%q %s %q %T %v", f.Name, f.Method, f.sql, f.Value, f.Value)
+		}
+		sort.Strings(parts)
+		list[i] = strings.Join(parts, " OR ")
+	}
+	sort.Strings(list)
+	return strings.Join(list, " AND ")
+}
+
 // GetFilter returns filter by name
 func (q *Query) GetFilter(name string) (*Filter, error) {
 
@@ -446,6 +1260,101 @@ func (q *Query) GetFilter(name string) (*Filter, error) {
 	return nil, ErrFilterNotFound
 }
 
+// FiltersByName returns all filters with specified name
+func (q *Query) FiltersByName(name string) []*Filter {
+	var list []*Filter
+
+	for _, v := range q.Filters {
+		if v.Name == name {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// FiltersByMethod returns all filters with specified compare method
+func (q *Query) FiltersByMethod(m Method) []*Filter {
+	var list []*Filter
+
+	for _, v := range q.Filters {
+		if v.Method == m {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// FiltersByNameAndMethod returns all filters with specified name and compare method
+func (q *Query) FiltersByNameAndMethod(name string, m Method) []*Filter {
+	var list []*Filter
+
+	for _, v := range q.Filters {
+		if v.Name == name && v.Method == m {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// GroupFiltersByTable returns filters grouped by table taken from qualified names of filters
+// (eg. "users" for "users.id"). Filters with not qualified names and raw filters are under "" key.
+// Use ReplaceNames to qualify names of filters.
+func (q *Query) GroupFiltersByTable() map[string][]*Filter {
+	groups := make(map[string][]*Filter)
+
+	for _, v := range q.Filters {
+		table := ""
+		if !v.IsRaw() {
+			table = tableOfName(v.Name)
+		}
+		groups[table] = append(groups[table], v)
+	}
+
+	return groups
+}
+
+// ForTable returns copy of Query with only filters, fields and sorts which qualified names
+// belong to the table (eg. "users.id" belongs to "users").
+// OR statement is kept only if all its filters belong to the table.
+// Other settings of Query (limit, offset etc.) are copied as is.
+func (q *Query) ForTable(table string) *Query {
+	qNew := q.Clone()
+
+	units := qNew.filterUnits()
+	qNew.Filters = nil
+	for _, unit := range units {
+		belongs := true
+		for _, v := range unit {
+			if v.IsRaw() || tableOfName(v.Name) != table {
+				belongs = false
+				break
+			}
+		}
+		if belongs {
+			qNew.Filters = append(qNew.Filters, unit...)
+		}
+	}
+
+	qNew.Fields = nil
+	for _, v := range q.Fields {
+		if tableOfName(v) == table {
+			qNew.Fields = append(qNew.Fields, v)
+		}
+	}
+
+	qNew.Sorts = nil
+	for _, v := range q.Sorts {
+		if tableOfName(v.By) == table {
+			qNew.Sorts = append(qNew.Sorts, v)
+		}
+	}
+
+	return qNew
+}
+
 // Replacer struct for ReplaceNames method
 type Replacer map[string]string
 
@@ -464,6 +1373,10 @@ func (q *Query) ReplaceNames(r Replacer) {
 		for i, v := range q.Filters {
 			if v.Name == name {
 				q.Filters[i].Name = newname
+				// keep method part of key, eg. "id[eq]" -> "newid[eq]"
+				if v.Key == name || strings.HasPrefix(v.Key, name+"[") {
+					q.Filters[i].Key = newname + v.Key[len(name):]
+				}
 			}
 		}
 		for i, v := range q.Fields {
@@ -483,6 +1396,29 @@ func (q *Query) ReplaceNames(r Replacer) {
 // Where returns list of filters for WHERE statement
 // return example: `id > 0 AND email LIKE 'some@email.com'`
 func (q *Query) Where() string {
+	return q.where("", 0)
+}
+
+// WhereWithPrefix returns list of filters for WHERE statement like Where does
+// but prefixes with tablePrefix the names of filters which aren't qualified by table yet.
+// Filters of the Query are not modified.
+// return example: `u.id > 0 AND r.name LIKE ?`
+func (q *Query) WhereWithPrefix(tablePrefix string) string {
+	return q.where(tablePrefix, 0)
+}
+
+// WhereWithPlaceholderOffset returns list of filters for WHERE statement with
+// PostgreSQL-style numbered placeholders ($1, $2, ...) and arguments for them.
+// startAt is a count of placeholders which go before the WHERE statement,
+// so the first placeholder of the statement will be $startAt+1.
+// return example: `id > $3 AND email LIKE $4`
+func (q *Query) WhereWithPlaceholderOffset(startAt int) (string, []interface{}) {
+	return q.where("", startAt+1), q.whereArgs()
+}
+
+// where builds WHERE statement, tablePrefix is optional.
+// If argNum greater then 0 placeholders are numbered starting from argNum.
+func (q *Query) where(tablePrefix string, argNum int) string {
 
 	if len(q.Filters) == 0 {
 		return ""
@@ -512,7 +1448,19 @@ func (q *Query) Where() string {
 			prefix = " AND "
 		}
 
+		if len(tablePrefix) > 0 && !filter.IsRaw() && !strings.Contains(filter.Name, ".") {
+			prefixed := *filter
+			prefixed.Name = tablePrefix + "." + filter.Name
+			filter = &prefixed
+		}
+
 		if a, err := filter.Where(); err == nil {
+			if q.nullAsArg && isNullFilter(filter) {
+				a = fmt.Sprintf("%s %s ?", filter.Name, translateMethods[filter.Method])
+			}
+			if argNum > 0 && (!filter.IsRaw() || filter.Value != nil) {
+				a, argNum = numberPlaceholders(a, argNum)
+			}
 			where += fmt.Sprintf("%s%s%s", prefix, a, suffix)
 		} else {
 			continue
@@ -523,6 +1471,47 @@ func (q *Query) Where() string {
 	return where
 }
 
+// WhereSlice returns conditions of WHERE statement as separate strings without joining them by AND,
+// e.g. to pass them to query builders which accept individual conditions.
+// Filters of OR statement are returned as one condition in parentheses.
+// Filters which can't be represented in SQL are skipped.
+//
+// Return example: `["id = ?", "(email LIKE ? OR name LIKE ?)"]`
+func (q *Query) WhereSlice() []string {
+	var conds []string
+	for _, u := range q.unitQueries() {
+		if cond := u.where("", 0); cond != "" {
+			conds = append(conds, cond)
+		}
+	}
+	return conds
+}
+
+// ArgsSlice returns arguments of conditions returned by WhereSlice,
+// the i-th element contains arguments of the i-th condition.
+//
+// Return example: `[[%tim% %tim%] [1 2]]`
+func (q *Query) ArgsSlice() [][]interface{} {
+	var args [][]interface{}
+	for _, u := range q.unitQueries() {
+		if u.where("", 0) != "" {
+			args = append(args, u.whereArgs())
+		}
+	}
+	return args
+}
+
+// unitQueries returns queries with filters of a single unit (a filter or a whole OR statement)
+// and settings which affect WHERE statement
+func (q *Query) unitQueries() []*Query {
+	units := q.filterUnits()
+	queries := make([]*Query, len(units))
+	for i, unit := range units {
+		queries[i] = &Query{Filters: unit, nullAsArg: q.nullAsArg}
+	}
+	return queries
+}
+
 // WHERE returns list of filters for WHERE SQL statement with `WHERE` word
 //
 // Return example: ` WHERE id > 0 AND email LIKE 'some@email.com'`
@@ -536,9 +1525,28 @@ func (q *Query) WHERE() string {
 	return " WHERE " + q.Where()
 }
 
-// Args returns slice of arguments for WHERE statement
+// Args returns slice of arguments for WHERE statement.
+// Arguments of CTEs go before arguments of filters.
 func (q *Query) Args() []interface{} {
 
+	return append(q.withArgs(), q.whereArgs()...)
+}
+
+// withArgs returns slice of arguments of CTEs only
+func (q *Query) withArgs() []interface{} {
+
+	args := make([]interface{}, 0)
+
+	for _, c := range q.ctes {
+		args = append(args, c.args...)
+	}
+
+	return args
+}
+
+// whereArgs returns slice of arguments of filters only
+func (q *Query) whereArgs() []interface{} {
+
 	args := make([]interface{}, 0)
 
 	if len(q.Filters) == 0 {
@@ -547,7 +1555,7 @@ func (q *Query) Args() []interface{} {
 
 	for i := 0; i < len(q.Filters); i++ {
 		filter := q.Filters[i]
-		if (filter.Method == IS || filter.Method == NOT) && filter.Value == NULL {
+		if !q.nullAsArg && isNullFilter(filter) {
 			continue
 		}
 
@@ -561,19 +1569,426 @@ func (q *Query) Args() []interface{} {
 	return args
 }
 
+// ArgsWithOffset returns slice of arguments of filters for the WHERE statement
+// which goes after startAt placeholders of outer SQL statement, the same as
+// WhereWithPlaceholderOffset returns. Arguments of CTEs aren't included.
+// end is the position of the last argument: startAt + len(args),
+// so placeholders after the WHERE statement start from end+1.
+func (q *Query) ArgsWithOffset(startAt int) (args []interface{}, end int) {
+	args = q.whereArgs()
+	return args, startAt + len(args)
+}
+
+// CTE registers a named common table expression which goes to the WITH clause of SQL statement.
+// Multiple CTEs are chained in order of registration.
+// innerArgs are arguments of innerSQL, they go before arguments of filters in Args().
+//
+// Example: q.CTE("active", "SELECT id FROM users WHERE active = ?", []interface{}{true})
+func (q *Query) CTE(name, innerSQL string, innerArgs []interface{}) *Query {
+	q.ctes = append(q.ctes, cte{
+		name: name,
+		sql:  innerSQL,
+		args: innerArgs,
+	})
+	return q
+}
+
+// WITH returns word WITH with list of registered CTEs or empty string if nothing registered
+//
+// Return example: `WITH active AS (SELECT id FROM users WHERE active = ?) `
+func (q *Query) WITH() string {
+	if len(q.ctes) == 0 {
+		return ""
+	}
+
+	list := make([]string, len(q.ctes))
+	for i, c := range q.ctes {
+		list[i] = fmt.Sprintf("%s AS (%s)", c.name, c.sql)
+	}
+
+	return fmt.Sprintf("WITH %s ", strings.Join(list, ", "))
+}
+
+// Window registers a named window which goes to the WINDOW clause of SQL statement.
+// partitionBy or orderBy could be empty.
+// Use the name of window in fields, e.g. q.AddField("ROW_NUMBER() OVER (w)")
+func (q *Query) Window(name, partitionBy, orderBy string) *Query {
+	q.windows = append(q.windows, window{
+		name:        name,
+		partitionBy: partitionBy,
+		orderBy:     orderBy,
+	})
+	return q
+}
+
+// WINDOW returns word WINDOW with list of registered windows or empty string if nothing registered
+//
+// Return example: ` WINDOW w AS (PARTITION BY user_id ORDER BY created_at DESC)`
+func (q *Query) WINDOW() string {
+	if len(q.windows) == 0 {
+		return ""
+	}
+
+	list := make([]string, len(q.windows))
+	for i, w := range q.windows {
+		var parts []string
+		if len(w.partitionBy) > 0 {
+			parts = append(parts, "PARTITION BY "+w.partitionBy)
+		}
+		if len(w.orderBy) > 0 {
+			parts = append(parts, "ORDER BY "+w.orderBy)
+		}
+		list[i] = fmt.Sprintf("%s AS (%s)", w.name, strings.Join(parts, " "))
+	}
+
+	return fmt.Sprintf(" WINDOW %s", strings.Join(list, ", "))
+}
+
+// Row-locking clauses:
+const (
+	lockForUpdate           = " FOR UPDATE"
+	lockForUpdateNoWait     = " FOR UPDATE NOWAIT"
+	lockForUpdateSkipLocked = " FOR UPDATE SKIP LOCKED"
+	lockForShare            = " FOR SHARE"
+)
+
+// ForUpdate sets or unsets FOR UPDATE row-locking clause of SQL statement.
+// Only one locking clause is used at a time, the last call wins.
+func (q *Query) ForUpdate(b bool) *Query {
+	return q.setLock(b, lockForUpdate)
+}
+
+// ForUpdateNoWait sets FOR UPDATE NOWAIT row-locking clause of SQL statement
+func (q *Query) ForUpdateNoWait() *Query {
+	return q.setLock(true, lockForUpdateNoWait)
+}
+
+// ForUpdateSkipLocked sets FOR UPDATE SKIP LOCKED row-locking clause of SQL statement
+func (q *Query) ForUpdateSkipLocked() *Query {
+	return q.setLock(true, lockForUpdateSkipLocked)
+}
+
+// ForShare sets or unsets FOR SHARE row-locking clause of SQL statement.
+// Only one locking clause is used at a time, the last call wins.
+func (q *Query) ForShare(b bool) *Query {
+	return q.setLock(b, lockForShare)
+}
+
+// setLock sets lock clause if b is true or unsets it if b is false and same kind of clause is set
+func (q *Query) setLock(b bool, lock string) *Query {
+	if b {
+		q.lock = lock
+	} else if strings.HasPrefix(q.lock, lock) {
+		q.lock = ""
+	}
+	return q
+}
+
+// LOCK returns row-locking clause
+//
+// Return example: ` FOR UPDATE SKIP LOCKED`
+func (q *Query) LOCK() string {
+	return q.lock
+}
+
+// RETURNING returns word RETURNING with fields separated by comma (",")
+// or with star ("*") if nothing provided. Use it with UPDATE/DELETE statements for PostgreSQL.
+//
+// Return example: ` RETURNING id, email`
+func (q *Query) RETURNING(fields ...string) string {
+	if len(fields) == 0 {
+		return " RETURNING *"
+	}
+	return fmt.Sprintf(" RETURNING %s", strings.Join(fields, ", "))
+}
+
+// UPDATESet returns UPDATE statement which sets setFields for rows matched by filters
+// and arguments for it. Fields in SET are ordered alphabetically.
+// Returns ErrRequired if setFields is empty.
+//
+// Return example: `UPDATE users SET name = ?, status = ? WHERE id = ?`
+func (q *Query) UPDATESet(table string, setFields map[string]interface{}) (string, []interface{}, error) {
+	if len(setFields) == 0 {
+		return "", nil, ErrRequired
+	}
+
+	names := sortedKeys(setFields)
+
+	args := q.withArgs()
+	set := make([]string, len(names))
+	for i, name := range names {
+		set[i] = name + " = ?"
+		args = append(args, setFields[name])
+	}
+	args = append(args, q.whereArgs()...)
+
+	return fmt.Sprintf("%sUPDATE %s SET %s%s", q.WITH(), table, strings.Join(set, ", "), q.WHERE()), args, nil
+}
+
+// IncrementSQL returns UPDATE statement which atomically adds amount to field
+// for rows matched by filters and arguments for it. Use negative amount to decrement.
+//
+// Return example: `UPDATE posts SET views = views + ? WHERE id = ?`
+func (q *Query) IncrementSQL(table, field string, amount int) (string, []interface{}) {
+	args := q.withArgs()
+	args = append(args, amount)
+	args = append(args, q.whereArgs()...)
+
+	return fmt.Sprintf("%sUPDATE %s SET %s = %s + ?%s", q.WITH(), table, field, field, q.WHERE()), args
+}
+
+// InsertSQL returns INSERT statement for data and arguments for it.
+// Columns are ordered alphabetically. Filters of Query aren't used.
+// Returns ErrRequired if data is empty.
+//
+// Return example: `INSERT INTO users (email, name) VALUES (?, ?)`
+func (q *Query) InsertSQL(table string, data map[string]interface{}) (string, []interface{}, error) {
+	if len(data) == 0 {
+		return "", nil, ErrRequired
+	}
+
+	cols := sortedKeys(data)
+
+	args := q.withArgs()
+	for _, col := range cols {
+		args = append(args, data[col])
+	}
+
+	return fmt.Sprintf("%sINSERT INTO %s (%s) VALUES (%s)",
+		q.WITH(), table, strings.Join(cols, ", "), placeholders(len(cols))), args, nil
+}
+
+// UpsertSQL returns PostgreSQL INSERT ... ON CONFLICT statement for data and arguments for it.
+// The statement is for PostgreSQL and SQLite only, use UpsertSQLMySQL for MySQL.
+// Columns which aren't in conflictKeys are updated by values from EXCLUDED row.
+// If there are no columns to update or no conflictKeys it does nothing on conflict.
+// Returns ErrRequired if data is empty.
+//
+// Return example: `INSERT INTO users (email, name) VALUES (?, ?) ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name`
+func (q *Query) UpsertSQL(table string, data map[string]interface{}, conflictKeys []string) (string, []interface{}, error) {
+	sql, args, err := q.InsertSQL(table, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var set []string
+	for _, col := range sortedKeys(data) {
+		if !stringInSlice(col, conflictKeys) {
+			set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+		}
+	}
+
+	switch {
+	case len(conflictKeys) == 0:
+		sql += " ON CONFLICT DO NOTHING"
+	case len(set) == 0:
+		sql += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(conflictKeys, ", "))
+	default:
+		sql += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflictKeys, ", "), strings.Join(set, ", "))
+	}
+
+	return sql, args, nil
+}
+
+// UpsertSQLMySQL returns MySQL INSERT ... ON DUPLICATE KEY UPDATE statement for data and arguments for it.
+// Columns which aren't in conflictKeys are updated by inserted values. MySQL detects conflicts
+// by unique keys of the table itself, conflictKeys are only excluded from the update.
+// If there are no columns to update the first column is assigned to itself, so nothing changes on conflict.
+// Returns ErrRequired if data is empty.
+//
+// Return example: `INSERT INTO users (email, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name)`
+func (q *Query) UpsertSQLMySQL(table string, data map[string]interface{}, conflictKeys []string) (string, []interface{}, error) {
+	sql, args, err := q.InsertSQL(table, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cols := sortedKeys(data)
+
+	var set []string
+	for _, col := range cols {
+		if !stringInSlice(col, conflictKeys) {
+			set = append(set, fmt.Sprintf("%s = VALUES(%s)", col, col))
+		}
+	}
+	if len(set) == 0 {
+		set = append(set, fmt.Sprintf("%s = %s", cols[0], cols[0]))
+	}
+
+	return sql + " ON DUPLICATE KEY UPDATE " + strings.Join(set, ", "), args, nil
+}
+
+// StoredProcSQL returns CALL statement of stored procedure with arguments of filters
+// followed by ORDER BY list, limit and offset as positional parameters.
+// ORDER BY list, limit and offset are always passed to keep positions of parameters stable.
+// ORDER BY list is passed as an opaque string (result of Order), the procedure has to
+// validate it and build dynamic SQL to use it for sorting.
+//
+// Return example: `CALL search_users(?, ?, ?, ?, ?)` with args [1 tim@example.com "id DESC" 10 0]
+func (q *Query) StoredProcSQL(procName string) (string, []interface{}) {
+	args := q.storedProcArgs()
+	return fmt.Sprintf("CALL %s(%s)", procName, placeholders(len(args))), args
+}
+
+// StoredFuncSQL returns PostgreSQL SELECT statement of set-returning function
+// with PostgreSQL-style numbered placeholders ($1, $2, ...) and the same arguments as StoredProcSQL.
+//
+// Return example: `SELECT * FROM search_users($1, $2, $3, $4, $5)` with args [1 tim@example.com "id DESC" 10 0]
+func (q *Query) StoredFuncSQL(procName string) (string, []interface{}) {
+	args := q.storedProcArgs()
+	list, _ := numberPlaceholders(placeholders(len(args)), 1)
+	return fmt.Sprintf("SELECT * FROM %s(%s)", procName, list), args
+}
+
+// storedProcArgs returns arguments of filters followed by ORDER BY list, limit and offset
+func (q *Query) storedProcArgs() []interface{} {
+	args := q.whereArgs()
+	return append(args, q.Order(), q.Limit, q.Offset)
+}
+
+// SetRequireFilterForDelete sets behavior for DELETE to refuse statement without filters
+func (q *Query) SetRequireFilterForDelete(require bool) *Query {
+	q.requireFilterForDelete = require
+	return q
+}
+
+// DELETE returns DELETE statement for rows matched by filters.
+//
+// Attention! If SetRequireFilterForDelete(true) and there are no filters it returns
+// empty string, callers MUST check for it before execution. Prefer DeleteWhereSQL
+// which returns ErrRequired in this case.
+//
+// Return example: `DELETE FROM users WHERE id = ?`
+func (q *Query) DELETE(table string) string {
+	if q.requireFilterForDelete && len(q.Filters) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%sDELETE FROM %s%s", q.WITH(), table, q.WHERE())
+}
+
+// DELETEArgs returns slice of arguments for DELETE statement, it's the same as Args
+func (q *Query) DELETEArgs() []interface{} {
+	return q.Args()
+}
+
 // SQL returns whole SQL statement
 func (q *Query) SQL(table string) string {
+	return q.sql(q.selectClause(), table, q.WHERE())
+}
+
+// SelectSQL returns whole SELECT statement, it's the same as SQL
+func (q *Query) SelectSQL(table string) string {
+	return q.SQL(table)
+}
+
+// UpdateWhereSQL returns UPDATE statement with setClause for rows matched by filters
+// and arguments for it: setArgs go before arguments of filters.
+//
+// Return example: `UPDATE users SET status = ?, updated_at = NOW() WHERE id = ?`
+func (q *Query) UpdateWhereSQL(table string, setClause string, setArgs []interface{}) (string, []interface{}) {
+	args := q.withArgs()
+	args = append(args, setArgs...)
+	args = append(args, q.whereArgs()...)
+
+	return fmt.Sprintf("%sUPDATE %s SET %s%s", q.WITH(), table, setClause, q.WHERE()), args
+}
+
+// DeleteWhereSQL returns DELETE statement for rows matched by filters and arguments for it.
+// Returns ErrRequired if SetRequireFilterForDelete(true) and there are no filters.
+//
+// Return example: `DELETE FROM users WHERE id = ?`
+func (q *Query) DeleteWhereSQL(table string) (string, []interface{}, error) {
+	if q.requireFilterForDelete && len(q.Filters) == 0 {
+		return "", nil, ErrRequired
+	}
+	return q.DELETE(table), q.DELETEArgs(), nil
+}
+
+// DebugSQL returns whole SQL statement like SQL does but with arguments inlined
+// instead of placeholders: strings and times are quoted by single quotes, numbers
+// and booleans are unquoted, nil is NULL.
+//
+// WARNING! Use it ONLY for logging and debugging. Quoting of values is simplified,
+// the result is NOT safe against SQL injections and must NEVER be executed.
+//
+// Return example: `SELECT * FROM users WHERE id = 1 AND name = 'tim'`
+func (q *Query) DebugSQL(table string) string {
+	qDebug := q.Clone()
+
+	for i, c := range qDebug.ctes {
+		qDebug.ctes[i] = cte{name: c.name, sql: inlineArgs(c.sql, c.args)}
+	}
+
+	qDebug.Filters = make([]*Filter, 0, len(q.Filters))
+	for _, f := range q.Filters {
+		cond, err := f.Where()
+		if err != nil {
+			continue
+		}
+		// arguments of NULL filters are inlined as NULL keyword by Where itself,
+		// raw conditions without arguments could contain literal `?` (eg. jsonb operator)
+		if !isNullFilter(f) && (!f.IsRaw() || f.Value != nil) {
+			args, err := f.Args()
+			if err != nil {
+				continue
+			}
+			cond = inlineArgs(cond, args)
+		}
+		qDebug.Filters = append(qDebug.Filters, &Filter{Name: cond, Method: raw, OR: f.OR})
+	}
+	qDebug.nullAsArg = false
+
+	return qDebug.SQL(table)
+}
+
+// SQLWithSelect returns whole SQL statement with custom list of columns for SELECT
+//
+// Return example: `SELECT COUNT(*) FROM users WHERE id > ?`
+func (q *Query) SQLWithSelect(selectClause string, table string) string {
+	return q.sql(selectClause, table, q.WHERE())
+}
+
+// selectClause returns columns set by SetSelectAs or fields of Query
+func (q *Query) selectClause() string {
+	if q.selectAs != "" {
+		return q.selectAs
+	}
+	return q.Select()
+}
+
+// sql builds whole SQL statement with provided SELECT, FROM and WHERE parts
+func (q *Query) sql(selectClause, from, where string) string {
 	return fmt.Sprintf(
-		"%s FROM %s%s%s%s%s",
-		q.SELECT(),
-		table,
-		q.WHERE(),
+		"%sSELECT %s FROM %s%s%s%s%s%s%s",
+		q.WITH(),
+		selectClause,
+		from,
+		where,
+		q.WINDOW(),
 		q.ORDER(),
 		q.LIMIT(),
 		q.OFFSET(),
+		q.LOCK(),
 	)
 }
 
+// SQLWithAlias returns whole SQL statement for table with alias.
+// Names of filters which aren't qualified by table are prefixed with alias in WHERE statement.
+//
+// Return example: `SELECT * FROM users u WHERE u.id = ?`
+func (q *Query) SQLWithAlias(table, alias string) string {
+	var where string
+	if len(q.Filters) > 0 {
+		where = " WHERE " + q.WhereWithPrefix(alias)
+	}
+	return q.sql(q.selectClause(), table+" "+alias, where)
+}
+
+// SQLAs is a short form of SQLWithAlias
+func (q *Query) SQLAs(table, alias string) string {
+	return q.SQLWithAlias(table, alias)
+}
+
 // SetUrlQuery change url in the Query for parsing
 // uses when you need provide Query from http.HandlerFunc(w http.ResponseWriter, r *http.Request)
 // you can do q.SetUrlValues(r.URL.Query())
@@ -593,6 +2008,26 @@ func (q *Query) SetUrlString(Url string) error {
 	return err
 }
 
+// ParseString sets raw URL string and parses it, it's a short form of
+// SetUrlString and Parse calls. Returns the first error.
+func (q *Query) ParseString(raw string) error {
+	if err := q.SetUrlString(raw); err != nil {
+		return err
+	}
+	return q.Parse()
+}
+
+// ParseValues sets query of URL and parses it, it's a short form of
+// SetUrlQuery and Parse calls.
+func (q *Query) ParseValues(v url.Values) error {
+	return q.SetUrlQuery(v).Parse()
+}
+
+// ParseRequest parses query of URL of http.Request
+func (q *Query) ParseRequest(r *http.Request) error {
+	return q.ParseValues(r.URL.Query())
+}
+
 // SetValidations change validations rules for the instance
 func (q *Query) SetValidations(v Validations) *Query {
 	q.validations = v
@@ -619,13 +2054,43 @@ func NewParse(q url.Values, v Validations) (*Query, error) {
 	return query, query.Parse()
 }
 
+// NewFromRequest creates new Query instance and parses query of URL of http.Request
+func NewFromRequest(r *http.Request, v Validations) (*Query, error) {
+	query := New().SetValidations(v)
+	return query, query.ParseRequest(r)
+}
+
+// SetOnAfterParse sets func which is called at the end of every Parse with its result.
+// Use it to collect metrics or to log parsed queries, e.g. count of filters or errors.
+func (q *Query) SetOnAfterParse(fn func(q *Query, err error)) *Query {
+	q.onAfterParse = fn
+	return q
+}
+
 // Parse parses the query of URL
 // as query you can use standart http.Request query by r.URL.Query()
-func (q *Query) Parse() (err error) {
+func (q *Query) Parse() error {
+	start := time.Now()
+	err := q.parse()
+	if q.logParse != nil {
+		q.logParse(q, err, time.Since(start))
+	}
+	if q.onAfterParse != nil {
+		q.onAfterParse(q, err)
+	}
+	return err
+}
+
+// parse does the work of Parse
+func (q *Query) parse() (err error) {
 
 	// clean previously parsed filters
 	q.cleanFilters()
 
+	if q.keyPatternErr != nil {
+		return q.keyPatternErr
+	}
+
 	// construct a slice with required names of filters
 	requiredNames := q.requiredNames()
 
@@ -654,6 +2119,8 @@ func (q *Query) Parse() (err error) {
 			if len(values) == 0 {
 				return errors.Wrap(ErrBadFormat, key)
 			}
+			key, values = q.arrayValues(key, values)
+			key, values = q.multiValues(key, values)
 			for _, value := range values {
 				err = q.parseFilter(key, value)
 				if err != nil {
@@ -667,6 +2134,8 @@ func (q *Query) Parse() (err error) {
 		}
 	}
 
+	q.prioritizeSorts()
+
 	// check required filters
 
 	for requiredName := range requiredNames {
@@ -675,6 +2144,10 @@ func (q *Query) Parse() (err error) {
 		}
 	}
 
+	if q.requireFilters && len(q.Filters) == 0 {
+		return errors.Wrap(ErrRequired, "filters")
+	}
+
 	return nil
 }
 
@@ -744,13 +2217,13 @@ func (q *Query) parseFilter(key, value string) error {
 				return errors.Wrap(ErrEmptyValue, key)
 			}
 
-			filter, err := newFilter(key, v, q.delimiterIN, q.validations)
+			filter, err := q.newFilter(key, v)
 
 			if err != nil {
-				if err == ErrValidationNotFound {
+				if err == ErrValidationNotFound || err == ErrInvalidCharacter {
 					if q.ignoreUnknown {
 						continue
-					} else {
+					} else if err == ErrValidationNotFound {
 						return errors.Wrap(ErrFilterNotFound, key)
 					}
 				}
@@ -767,25 +2240,121 @@ func (q *Query) parseFilter(key, value string) error {
 			}
 
 			q.Filters = append(q.Filters, filter)
+			if err := q.validate(len(q.Filters) - 1); err != nil {
+				return err
+			}
 		}
 	} else { // Single filter
-		filter, err := newFilter(key, value, q.delimiterIN, q.validations)
+		filter, err := q.newFilter(key, value)
 		if err != nil {
-			if err == ErrValidationNotFound {
-				err = ErrFilterNotFound
+			if err == ErrValidationNotFound || err == ErrInvalidCharacter {
 				if q.ignoreUnknown {
 					return nil
 				}
+				if err == ErrValidationNotFound {
+					err = ErrFilterNotFound
+				}
 			}
 			return errors.Wrap(err, key)
 		}
 
 		q.Filters = append(q.Filters, filter)
+		if err := q.validate(len(q.Filters) - 1); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// validate runs validation funcs of parsed filters starting from index from.
+// parseFilter calls it right after each filter is converted, so errors are
+// reported in the same order as filters appear in the URL.
+// Special filters and filters with nil validation func are skipped.
+// Filters starting from invalid one are removed like parsing stopped on it.
+func (q *Query) validate(from int) error {
+	for i := from; i < len(q.Filters); i++ {
+		f := q.Filters[i]
+		validate, _ := detectValidation(f.Name, q.validations)
+		if validate == nil || isNotNull(f) {
+			continue
+		}
+		if err := f.validate(validate); err != nil {
+			for j := i; j < len(q.Filters); j++ {
+				q.Filters[j] = nil
+			}
+			q.Filters = q.Filters[:i]
+			return errors.Wrap(err, f.Key)
+		}
+	}
+	return nil
+}
+
+// arrayValues converts PHP style array parameter `id[]` to IN filter
+// if ArrayParamStyleBracket is set
+func (q *Query) arrayValues(key string, values []string) (string, []string) {
+	if q.arrayParamStyle != ArrayParamStyleBracket || !strings.HasSuffix(key, "[]") {
+		return key, values
+	}
+	for _, v := range values {
+		if strings.Contains(v, q.delimiterOR) {
+			return key, values
+		}
+	}
+	return strings.TrimSuffix(key, "[]") + "[in]", []string{strings.Join(values, q.delimiterIN)}
+}
+
+// multiValues applies multi value mode to repeated parameter of URL
+func (q *Query) multiValues(key string, values []string) (string, []string) {
+	if len(values) < 2 {
+		return key, values
+	}
+
+	switch q.multiValueMode {
+	case MultiValueFirst:
+		return key, values[:1]
+	case MultiValueIN:
+		f := &Filter{}
+		if err := f.parseKey(key); err != nil {
+			return key, values
+		}
+		for _, v := range values {
+			if strings.Contains(v, q.delimiterOR) {
+				return key, values
+			}
+		}
+		switch f.Method {
+		case EQ, IN:
+			return f.Name + "[in]", []string{strings.Join(values, q.delimiterIN)}
+		case NE, NIN:
+			return f.Name + "[nin]", []string{strings.Join(values, q.delimiterIN)}
+		}
+	}
+
+	return key, values
+}
+
+// newFilter creates a filter using validations of the Query.
+// Special filters which have no validation are parsed as strings.
+func (q *Query) newFilter(key, value string) (*Filter, error) {
+	pattern := q.keyPattern
+	if pattern == nil {
+		pattern = defaultKeyPattern
+	}
+	if !pattern.MatchString(key) {
+		return nil, ErrInvalidCharacter
+	}
+
+	filter, err := newFilter(key, value, q.delimiterIN, q.validations)
+	if err == ErrValidationNotFound && len(q.specialFilters) > 0 {
+		f := &Filter{}
+		if e := f.parseKey(key); e == nil && q.specialFilters[f.Name] {
+			return newFilter(key, value, q.delimiterIN, Validations{f.Name: nil})
+		}
+	}
+	return filter, err
+}
+
 // clean the filters slice
 func (q *Query) cleanFilters() {
 	if len(q.Filters) > 0 {
@@ -794,6 +2363,7 @@ func (q *Query) cleanFilters() {
 		}
 		q.Filters = nil
 	}
+	q.namedFilters = nil
 }
 
 func (q *Query) parseSort(value []string, validate ValidationFunc) error {
@@ -805,6 +2375,11 @@ func (q *Query) parseSort(value []string, validate ValidationFunc) error {
 		return ErrValidationNotFound
 	}
 
+	if q.allowNullSort && strings.ToUpper(strings.TrimSpace(value[0])) == NULL {
+		q.Sorts = []Sort{}
+		return nil
+	}
+
 	list := value
 	if strings.Contains(value[0], q.delimiterIN) {
 		list = strings.Split(value[0], q.delimiterIN)
@@ -866,6 +2441,14 @@ func (q *Query) parseFields(value []string, validate ValidationFunc) error {
 
 	list = cleanSliceString(list)
 
+	if len(q.availableFields) > 0 && len(list) > 0 && list[0][0] == '-' {
+		var err error
+		list, err = q.excludeFields(list)
+		if err != nil {
+			return err
+		}
+	}
+
 	if validate != nil {
 		for _, v := range list {
 			if err := validate(v); err != nil {
@@ -878,6 +2461,27 @@ func (q *Query) parseFields(value []string, validate ValidationFunc) error {
 	return nil
 }
 
+// excludeFields returns available fields except excluded ones.
+// Every element of excluded must be prefixed by minus ("-").
+func (q *Query) excludeFields(excluded []string) ([]string, error) {
+	names := make([]string, len(excluded))
+	for i, v := range excluded {
+		if len(v) < 2 || v[0] != '-' {
+			return nil, ErrBadFormat
+		}
+		names[i] = v[1:]
+	}
+
+	list := make([]string, 0, len(q.availableFields))
+	for _, v := range q.availableFields {
+		if !stringInSlice(v, names) {
+			list = append(list, v)
+		}
+	}
+
+	return list, nil
+}
+
 func (q *Query) parseOffset(value []string, validate ValidationFunc) error {
 
 	if len(value) != 1 {
@@ -1,8 +1,13 @@
 package rqp
 
 import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -227,6 +232,9 @@ func TestWhere(t *testing.T) {
 		// not like, not ilike:
 		{url: "?u[nlike]=superman", expected: " WHERE u NOT LIKE ?"},
 		{url: "?u[nilike]=superman", expected: " WHERE u NOT ILIKE ?"},
+		// delimiter inside of LIKE pattern:
+		{url: "?u[like]=*smith,%20john*", expected: " WHERE u LIKE ?"},
+		{url: "?u[nilike]=smith,john", expected: " WHERE u NOT ILIKE ?"},
 
 		{url: "?id=1&name=superman", expected: " WHERE id = ?", ignore: true},
 		{url: "?id=1&name=superman&s[like]=super", expected: " WHERE id = ? AND s LIKE ?", expected2: " WHERE s LIKE ? AND id = ?", ignore: true},
@@ -358,6 +366,13 @@ func TestArgs(t *testing.T) {
 	assert.Contains(t, q.Args(), "%www%")
 	assert.Contains(t, q.Args(), "www1")
 	assert.Contains(t, q.Args(), "www2")
+
+	// LIKE pattern isn't split by delimiter
+	URL, err = url.Parse("?name[like]=*smith,%20john*")
+	assert.NoError(t, err)
+	q, err = NewParse(URL.Query(), Validations{"name": nil})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"%smith, john%"}, q.Args())
 }
 
 func TestSQL(t *testing.T) {
@@ -398,6 +413,8 @@ func TestReplaceFiltersNames(t *testing.T) {
 
 	assert.Len(t, q.Filters, 2)
 	assert.True(t, q.HaveFilter("two"))
+	f, _ := q.GetFilter("two")
+	assert.Equal(t, "two", f.Key)
 
 	q.ReplaceNames(Replacer{
 		"another":    "r.another",
@@ -416,10 +433,38 @@ func TestReplaceFiltersNames(t *testing.T) {
 	assert.Equal(t, q.RemoveFilter("r.another"), errors.Cause(ErrFilterNotFound))
 	_, err = q.GetFilter("r.another")
 	assert.Equal(t, err, errors.Cause(ErrFilterNotFound))
-	f, _ := q.GetFilter("r.another")
+	f, _ = q.GetFilter("r.another")
 	assert.IsType(t, &Filter{}, f)
 }
 
+func TestReplaceNames_Key(t *testing.T) {
+	URL, err := url.Parse("?id[gte]=1&name[like]=*tim*&ids[in]=1,2")
+	assert.NoError(t, err)
+
+	q, err := NewParse(URL.Query(), Validations{"id:int": nil, "name": nil, "ids:int": nil})
+	assert.NoError(t, err)
+	q.AddFilter("id", EQ, 5)
+
+	q.ReplaceNames(Replacer{"id": "u.id", "name": "u.name"})
+
+	for _, f := range q.Filters {
+		switch f.Name {
+		case "u.id":
+			if f.Method == GTE {
+				assert.Equal(t, "u.id[gte]", f.Key)
+			} else {
+				assert.Equal(t, "", f.Key) // added by AddFilter without key
+			}
+		case "u.name":
+			assert.Equal(t, "u.name[like]", f.Key)
+		case "ids":
+			assert.Equal(t, "ids[in]", f.Key)
+		default:
+			t.Errorf("unexpected filter %q", f.Name)
+		}
+	}
+}
+
 func TestRequiredFilter(t *testing.T) {
 	// required but not present
 	URL, err := url.Parse("?")
@@ -653,3 +698,1274 @@ func QueryEqual(t *testing.T, q, got *Query) {
 		t.Errorf("q.Filters = %v , want = %v", got.Filters, q.Filters)
 	}
 }
+
+func TestQuery_AllowSpecialFilters(t *testing.T) {
+	q := New().AllowSpecialFilters("flights")
+
+	assert.NoError(t, q.SetUrlString("?flights[is]=null"))
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, " WHERE flights IS NULL", q.WHERE())
+	assert.Len(t, q.Args(), 0)
+
+	assert.NoError(t, q.SetUrlString("?flights[not]=null|flights[eq]=SU100"))
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, " WHERE (flights IS NOT NULL OR flights = ?)", q.WHERE())
+
+	assert.NoError(t, q.SetUrlString("?hotels[is]=null"))
+	assert.Equal(t, ErrFilterNotFound, errors.Cause(q.Parse()))
+
+	QueryEqual(t, q, q.Clone())
+}
+
+func TestQuery_WhereWithPrefix(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1).AddFilter("r.name", LIKE, "*tim*")
+	q.AddORFilters(func(query *Query) {
+		query.AddFilter("firstname", ILIKE, "*hello*")
+		query.AddFilter("lastname", ILIKE, "*hello*")
+	})
+	q.AddFilterRaw("deleted_at IS NULL")
+
+	assert.Equal(t, "u.id = ? AND r.name LIKE ? AND (u.firstname ILIKE ? OR u.lastname ILIKE ?) AND deleted_at IS NULL", q.WhereWithPrefix("u"))
+	assert.Equal(t, "id = ? AND r.name LIKE ? AND (firstname ILIKE ? OR lastname ILIKE ?) AND deleted_at IS NULL", q.WhereWithPrefix(""))
+	// filters are not modified
+	assert.True(t, q.HaveFilter("id"))
+	assert.False(t, q.HaveFilter("u.id"))
+}
+
+func TestQuery_WhereSliceArgsSlice(t *testing.T) {
+	URL, _ := url.Parse("?email[like]=*tim*|name[like]=*tim*")
+	q := NewQV(URL.Query(), Validations{"email": nil, "name": nil})
+	assert.NoError(t, q.Parse())
+	q.AddFilter("id", IN, []int{1, 2}).
+		AddFilter("bad", "fake", 1).
+		AddFilter("deleted_at", IS, NULL).
+		AddFilterRaw("age > ?", 18)
+
+	assert.Equal(t, []string{
+		"(email LIKE ? OR name LIKE ?)",
+		"id IN (?, ?)",
+		"deleted_at IS NULL",
+		"age > ?",
+	}, q.WhereSlice())
+
+	assert.Nil(t, New().WhereSlice())
+
+	assert.Equal(t, [][]interface{}{
+		{"%tim%", "%tim%"},
+		{1, 2},
+		{},
+		{18},
+	}, q.ArgsSlice())
+	assert.Nil(t, New().ArgsSlice())
+
+	q.SetNullAsArg(true)
+	assert.Equal(t, "deleted_at IS ?", q.WhereSlice()[2])
+	assert.Equal(t, []interface{}{NULL}, q.ArgsSlice()[2])
+}
+
+func TestQuery_ArgsWithOffset(t *testing.T) {
+	q := New().AddFilter("id", GT, 1).AddFilter("email", LIKE, "*tim*")
+
+	args, end := q.ArgsWithOffset(2)
+	assert.Equal(t, []interface{}{1, "%tim%"}, args)
+	assert.Equal(t, 4, end)
+
+	// CTE arguments aren't included like in WhereWithPlaceholderOffset
+	q.CTE("active", "SELECT id FROM users WHERE active = ?", []interface{}{true})
+	args, end = q.ArgsWithOffset(0)
+	_, whereArgs := q.WhereWithPlaceholderOffset(0)
+	assert.Equal(t, whereArgs, args)
+	assert.Equal(t, 2, end)
+}
+
+func TestQuery_WhereWithPlaceholderOffset(t *testing.T) {
+	q := New().AddFilter("id", GT, 1).AddFilter("status", IN, []string{"new", "done"})
+	q.AddFilter("deleted_at", IS, NULL)
+	q.AddFilterRaw("data ? 'key'")
+	q.AddORFilters(func(query *Query) {
+		query.AddFilter("firstname", ILIKE, "*hello*")
+		query.AddFilter("lastname", ILIKE, "*hello*")
+	})
+
+	where, args := q.WhereWithPlaceholderOffset(2)
+	assert.Equal(t, "id > $3 AND status IN ($4, $5) AND deleted_at IS NULL AND data ? 'key' AND (firstname ILIKE $6 OR lastname ILIKE $7)", where)
+	assert.Equal(t, []interface{}{1, "new", "done", "%hello%", "%hello%"}, args)
+
+	where, _ = q.WhereWithPlaceholderOffset(0)
+	assert.True(t, strings.HasPrefix(where, "id > $1 AND"))
+}
+
+func TestQuery_SQLWithAlias(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1).AddFilter("r.name", EQ, "admin").AddSortBy("id", true).SetLimit(10)
+
+	assert.Equal(t, "SELECT * FROM users u WHERE u.id = ? AND r.name = ? ORDER BY id DESC LIMIT 10", q.SQLWithAlias("users", "u"))
+	assert.Equal(t, q.SQLWithAlias("users", "u"), q.SQLAs("users", "u"))
+	assert.Equal(t, "SELECT * FROM users u", New().SQLAs("users", "u"))
+}
+
+func TestQuery_SelectAs(t *testing.T) {
+	q := New().AddFilter("u.id", EQ, 1).SetLimit(10)
+	fields := map[string]string{"u.name": "user_name", "u.email": "user_email", "u.id": ""}
+
+	assert.Equal(t, "u.email AS user_email, u.id, u.name AS user_name", q.SelectAs(fields))
+	assert.Equal(t, "", q.SelectAs(nil))
+
+	q.SetSelectAs(fields)
+	assert.Equal(t, "SELECT u.email AS user_email, u.id, u.name AS user_name FROM users u WHERE u.id = ? LIMIT 10", q.SQL("users u"))
+	assert.Equal(t, q.SQL("users u"), q.Clone().SQL("users u"))
+	assert.Equal(t, "SELECT *", q.SELECT())
+
+	q.SetSelectAs(nil)
+	assert.Equal(t, "SELECT * FROM users u WHERE u.id = ? LIMIT 10", q.SQL("users u"))
+
+	assert.Equal(t, "SELECT COUNT(*) FROM users WHERE u.id = ? LIMIT 10", q.SQLWithSelect("COUNT(*)", "users"))
+}
+
+func TestQuery_CTE(t *testing.T) {
+	q := New().AddFilter("status", EQ, "new")
+	assert.Equal(t, "", q.WITH())
+
+	q.CTE("active", "SELECT id FROM users WHERE active = ?", []interface{}{true}).
+		CTE("recent", "SELECT id FROM orders WHERE created_at > ?", []interface{}{"2020-01-01"})
+
+	assert.Equal(t, "WITH active AS (SELECT id FROM users WHERE active = ?), recent AS (SELECT id FROM orders WHERE created_at > ?) SELECT * FROM recent WHERE status = ?", q.SQL("recent"))
+	assert.Equal(t, []interface{}{true, "2020-01-01", "new"}, q.Args())
+
+	where, args := q.WhereWithPlaceholderOffset(2)
+	assert.Equal(t, "status = $3", where)
+	assert.Equal(t, []interface{}{"new"}, args)
+
+	assert.Equal(t, q.SQL("recent"), q.Clone().SQL("recent"))
+}
+
+func TestQuery_Window(t *testing.T) {
+	q := New().AddField("id").AddField("ROW_NUMBER() OVER (w) AS rn").AddFilter("status", EQ, "new").AddSortBy("id", false)
+	assert.Equal(t, "", q.WINDOW())
+
+	q.Window("w", "user_id", "created_at DESC").Window("p", "", "id")
+
+	assert.Equal(t, " WINDOW w AS (PARTITION BY user_id ORDER BY created_at DESC), p AS (ORDER BY id)", q.WINDOW())
+	assert.Equal(t, "SELECT id, ROW_NUMBER() OVER (w) AS rn FROM orders WHERE status = ? WINDOW w AS (PARTITION BY user_id ORDER BY created_at DESC), p AS (ORDER BY id) ORDER BY id", q.SQL("orders"))
+	assert.Equal(t, q.SQL("orders"), q.Clone().SQL("orders"))
+}
+
+func TestQuery_ForUpdate(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1).SetLimit(1)
+	assert.Equal(t, "", q.LOCK())
+
+	q.ForUpdate(true)
+	assert.Equal(t, "SELECT * FROM jobs WHERE id = ? LIMIT 1 FOR UPDATE", q.SQL("jobs"))
+	assert.Equal(t, q.SQL("jobs"), q.Clone().SQL("jobs"))
+
+	q.ForUpdateSkipLocked()
+	assert.Equal(t, " FOR UPDATE SKIP LOCKED", q.LOCK())
+
+	q.ForUpdateNoWait()
+	assert.Equal(t, " FOR UPDATE NOWAIT", q.LOCK())
+
+	// ForShare(false) doesn't unset another kind of lock
+	q.ForShare(false)
+	assert.Equal(t, " FOR UPDATE NOWAIT", q.LOCK())
+
+	q.ForUpdate(false)
+	assert.Equal(t, "", q.LOCK())
+
+	q.ForShare(true)
+	assert.Equal(t, "SELECT * FROM jobs WHERE id = ? LIMIT 1 FOR SHARE", q.SQL("jobs"))
+
+	q.ForUpdate(true)
+	assert.Equal(t, " FOR UPDATE", q.LOCK())
+
+	q.ForUpdate(false)
+	assert.Equal(t, "SELECT * FROM jobs WHERE id = ? LIMIT 1", q.SQL("jobs"))
+}
+
+func TestQuery_SetMultiValueMode(t *testing.T) {
+	cases := []struct {
+		url      string
+		mode     MultiValueMode
+		expected string
+		args     []interface{}
+	}{
+		{url: "?id=1&id=2", mode: MultiValueAnd, expected: " WHERE id = ? AND id = ?", args: []interface{}{1, 2}},
+		{url: "?id=1&id=2", mode: MultiValueIN, expected: " WHERE id IN (?, ?)", args: []interface{}{1, 2}},
+		{url: "?id[ne]=1&id[ne]=2", mode: MultiValueIN, expected: " WHERE id NOT IN (?, ?)", args: []interface{}{1, 2}},
+		{url: "?id[in]=1,2&id[in]=3", mode: MultiValueIN, expected: " WHERE id IN (?, ?, ?)", args: []interface{}{1, 2, 3}},
+		{url: "?id[gt]=1&id[gt]=2", mode: MultiValueIN, expected: " WHERE id > ? AND id > ?", args: []interface{}{1, 2}},
+		{url: "?id=1&id=2", mode: MultiValueFirst, expected: " WHERE id = ?", args: []interface{}{1}},
+		{url: "?id=1", mode: MultiValueIN, expected: " WHERE id = ?", args: []interface{}{1}},
+	}
+	for _, c := range cases {
+		t.Run(c.url, func(t *testing.T) {
+			q := New().SetValidations(Validations{"id:int": nil}).SetMultiValueMode(c.mode)
+			assert.NoError(t, q.SetUrlString(c.url))
+			assert.NoError(t, q.Parse())
+			assert.Equal(t, c.expected, q.WHERE())
+			assert.Equal(t, c.args, q.Args())
+		})
+	}
+}
+
+func TestQuery_SetArrayParamStyle(t *testing.T) {
+	validations := Validations{"id:int": nil, "s": nil}
+
+	URL, _ := url.Parse("?id[]=1&id[]=2&s[]=a")
+	q := NewQV(URL.Query(), validations).SetArrayParamStyle(ArrayParamStyleBracket)
+	assert.NoError(t, q.Parse())
+	f, err := q.GetFilter("id")
+	assert.NoError(t, err)
+	assert.Equal(t, IN, f.Method)
+	assert.Equal(t, []int{1, 2}, f.Value)
+	f, err = q.GetFilter("s")
+	assert.NoError(t, err)
+	assert.Equal(t, IN, f.Method)
+	assert.Equal(t, "a", f.Value)
+	assert.Equal(t, ArrayParamStyleBracket, q.Clone().arrayParamStyle)
+
+	// by default `id[]` is EQ filter for every value
+	q = NewQV(URL.Query(), validations)
+	assert.NoError(t, q.Parse())
+	assert.Len(t, q.FiltersByNameAndMethod("id", EQ), 2)
+}
+
+func TestQuery_AddSortByWeighted(t *testing.T) {
+	q := New().AddSortBy("name", false).AddSortByWeighted("id", true, 10).AddSortByWeighted("pinned", true, -1)
+	assert.Equal(t, " ORDER BY pinned DESC, name, id DESC", q.ORDER())
+
+	q = New().SetValidations(Validations{"sort": In("id", "name", "email")})
+	assert.NoError(t, q.SetUrlString("?sort=-name,email"))
+	assert.NoError(t, q.Parse())
+	q.AddSortByWeighted("id", false, 1)
+	assert.Equal(t, " ORDER BY name DESC, email, id", q.ORDER())
+}
+
+func TestQuery_SetAvailableFields(t *testing.T) {
+	q := New().SetValidations(Validations{"fields": In("id", "name", "email", "password")})
+	q.SetAvailableFields("id", "name", "email", "password")
+
+	assert.NoError(t, q.SetUrlString("?fields=-password"))
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, []string{"id", "name", "email"}, q.Fields)
+
+	assert.NoError(t, q.SetUrlString("?fields=-password,-email"))
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, []string{"id", "name"}, q.Fields)
+
+	assert.NoError(t, q.SetUrlString("?fields=id,name"))
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, []string{"id", "name"}, q.Fields)
+
+	assert.NoError(t, q.SetUrlString("?fields=-password,email"))
+	assert.EqualError(t, q.Parse(), "fields: bad format")
+
+	// exclusion is not supported without available fields
+	q = New().SetValidations(Validations{"fields": In("id", "password")})
+	assert.NoError(t, q.SetUrlString("?fields=-password"))
+	assert.EqualError(t, q.Parse(), "fields: -password: not in scope")
+}
+
+func TestQuery_SetRequireFilters(t *testing.T) {
+	q := New().SetValidations(Validations{"name": nil, "sort": In("id")}).SetRequireFilters(true)
+
+	err := q.ParseString("/?sort=id&limit=10")
+	assert.Equal(t, ErrRequired, errors.Cause(err))
+	assert.EqualError(t, err, "filters: required")
+
+	assert.NoError(t, q.ParseString("/?name=tim"))
+	assert.True(t, q.Clone().requireFilters)
+
+	// filters added in code aren't user-supplied
+	q.AddFilter("id", EQ, 1)
+	assert.Equal(t, ErrRequired, errors.Cause(q.ParseString("/?limit=10")))
+
+	q.SetRequireFilters(false)
+	assert.NoError(t, q.ParseString("/?limit=10"))
+}
+
+func TestQuery_SetAllowNullSort(t *testing.T) {
+	q := New().SetValidations(Validations{"sort": In("id", "null")}).AddSortBy("name", false)
+
+	assert.NoError(t, q.SetUrlString("?sort=null"))
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, []Sort{{By: "null"}}, q.Sorts)
+
+	q.SetAllowNullSort(true)
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, []Sort{}, q.Sorts)
+	assert.Equal(t, "", q.ORDER())
+
+	assert.NoError(t, q.SetUrlString("?sort=NULL"))
+	q.AddSortBy("name", false)
+	assert.NoError(t, q.Parse())
+	assert.Len(t, q.Sorts, 0)
+
+	assert.True(t, q.Clone().allowNullSort)
+}
+
+func TestQuery_Page(t *testing.T) {
+	q := New()
+	assert.Equal(t, 1, q.Page())
+	assert.Equal(t, 0, q.PerPage())
+
+	q.SetOffset(20)
+	assert.Equal(t, 1, q.Page())
+
+	q.SetLimit(10)
+	assert.Equal(t, 3, q.Page())
+	assert.Equal(t, 10, q.PerPage())
+
+	q.SetOffset(25)
+	assert.Equal(t, 3, q.Page())
+
+	q.SetOffset(0)
+	assert.Equal(t, 1, q.Page())
+}
+
+func TestQuery_Has(t *testing.T) {
+	q := New()
+	assert.False(t, q.HasPagination())
+	assert.False(t, q.HasSorting())
+	assert.False(t, q.HasFilters())
+
+	q.SetOffset(10)
+	assert.True(t, q.HasPagination())
+	q.SetOffset(0).SetLimit(10)
+	assert.True(t, q.HasPagination())
+
+	q.AddSortBy("id", false)
+	assert.True(t, q.HasSorting())
+
+	q.AddFilter("id", EQ, 1)
+	assert.True(t, q.HasFilters())
+}
+
+func TestQuery_Count(t *testing.T) {
+	q := New()
+	assert.Equal(t, 0, q.FilterCount())
+	assert.Equal(t, 0, q.SortCount())
+	assert.Equal(t, 0, q.FieldCount())
+
+	q.AddFilter("id", EQ, 1).AddFilter("name", EQ, "tim")
+	q.AddSortBy("id", false)
+	q.AddField("id").AddField("name").AddField("email")
+
+	assert.Equal(t, 2, q.FilterCount())
+	assert.Equal(t, 1, q.SortCount())
+	assert.Equal(t, 3, q.FieldCount())
+}
+
+func TestQuery_FiltersByName(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil, "name": nil})
+	assert.NoError(t, q.SetUrlString("?id[gte]=1&id[lte]=10&name[like]=tim"))
+	assert.NoError(t, q.Parse())
+
+	assert.Len(t, q.FiltersByName("id"), 2)
+	assert.Len(t, q.FiltersByName("name"), 1)
+	assert.Len(t, q.FiltersByName("email"), 0)
+
+	assert.Len(t, q.FiltersByMethod(LIKE), 1)
+	assert.Len(t, q.FiltersByMethod(EQ), 0)
+
+	list := q.FiltersByNameAndMethod("id", GTE)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, 1, list[0].Value)
+	}
+	assert.Len(t, q.FiltersByNameAndMethod("name", GTE), 0)
+}
+
+func TestQuery_SortBy(t *testing.T) {
+	q := New().AddSortBy("name", false).AddSortBy("id", true)
+
+	s, ok := q.SortBy("id")
+	assert.True(t, ok)
+	assert.Equal(t, Sort{By: "id", Desc: true}, s)
+
+	_, ok = q.SortBy("email")
+	assert.False(t, ok)
+
+	s, i, ok := q.SortByIndex("id")
+	assert.True(t, ok)
+	assert.Equal(t, 1, i)
+	assert.True(t, s.Desc)
+	q.Sorts[i].Desc = false
+	assert.Equal(t, "name, id", q.Order())
+
+	_, i, ok = q.SortByIndex("email")
+	assert.False(t, ok)
+	assert.Equal(t, -1, i)
+}
+
+func TestQuery_ReplaceSort(t *testing.T) {
+	q := New().AddSortBy("name", false).AddSortBy("id", true)
+
+	assert.NoError(t, q.ReplaceSort("name", Sort{By: "lower(name)", Desc: true}))
+	assert.Equal(t, "lower(name) DESC, id DESC", q.Order())
+
+	assert.NoError(t, q.ReplaceSortDesc("id", false))
+	assert.Equal(t, "lower(name) DESC, id", q.Order())
+
+	assert.NoError(t, q.ReplaceSort("lower(name)", Sort{By: "name", SortPriority: 1}))
+	assert.Equal(t, "id, name", q.Order())
+
+	assert.Equal(t, ErrFilterNotFound, q.ReplaceSort("email", Sort{By: "email"}))
+	assert.Equal(t, ErrFilterNotFound, q.ReplaceSortDesc("email", true))
+}
+
+func TestQuery_AppendField(t *testing.T) {
+	q := New().AddField("name")
+
+	q.AppendField("email", "phone")
+	assert.Equal(t, []string{"name", "email", "phone"}, q.Fields)
+
+	q.PrependField("id", "uuid")
+	assert.Equal(t, []string{"id", "uuid", "name", "email", "phone"}, q.Fields)
+
+	q = New().PrependField("id").AppendField()
+	assert.Equal(t, "SELECT id", q.SELECT())
+}
+
+func TestQuery_RemoveField(t *testing.T) {
+	q := New().AppendField("id", "name", "id")
+
+	assert.NoError(t, q.RemoveField("id"))
+	assert.Equal(t, []string{"name", "id"}, q.Fields)
+	assert.Equal(t, ErrFilterNotFound, q.RemoveField("email"))
+
+	q.RemoveAllFields()
+	assert.Len(t, q.Fields, 0)
+	assert.Equal(t, "SELECT *", q.SELECT())
+}
+
+func TestQuery_ReplaceField(t *testing.T) {
+	q := New().AppendField("id", "name").AddFilter("id", EQ, 1).AddSortBy("id", false)
+
+	assert.NoError(t, q.ReplaceField("id", "u.id"))
+	assert.Equal(t, "SELECT u.id, name FROM users u WHERE u.id = ? ORDER BY id", q.SQLAs("users", "u"))
+	assert.True(t, q.HaveFilter("id"))
+	assert.True(t, q.HaveSortBy("id"))
+
+	assert.Equal(t, ErrFilterNotFound, q.ReplaceField("id", "users.id"))
+}
+
+func TestQuery_UniqueFilters(t *testing.T) {
+	t.Run("plain filters", func(t *testing.T) {
+		q := New().AddFilter("id", EQ, 1).AddFilter("id", EQ, 1).AddFilter("id", GT, 1).
+			AddFilter("status", IN, []string{"new", "done"}).AddFilter("status", IN, []string{"new", "done"})
+		q.UniqueFilters()
+		assert.Equal(t, "id = ? AND id > ? AND status IN (?, ?)", q.Where())
+	})
+
+	t.Run("parsed twice", func(t *testing.T) {
+		q := New().SetValidations(Validations{"id:int": nil})
+		assert.NoError(t, q.SetUrlString("?id=1&id=1"))
+		assert.NoError(t, q.Parse())
+		assert.Equal(t, "id = ?", q.UniqueFilters().Where())
+	})
+
+	t.Run("OR statement", func(t *testing.T) {
+		q := New().AddFilter("a", EQ, 1)
+		q.AddORFilters(func(query *Query) {
+			query.AddFilter("a", EQ, 1)
+			query.AddFilter("b", EQ, 2)
+			query.AddFilter("b", EQ, 2)
+		})
+		q.AddORFilters(func(query *Query) {
+			query.AddFilter("c", EQ, 3)
+			query.AddFilter("c", EQ, 3)
+		})
+		q.UniqueFilters()
+		assert.Equal(t, "a = ? AND (a = ? OR b = ?) AND c = ?", q.Where())
+		assert.Equal(t, []interface{}{1, 1, 2, 3}, q.Args())
+	})
+
+	t.Run("OR statement turned into plain filter", func(t *testing.T) {
+		q := New()
+		q.AddORFilters(func(query *Query) {
+			query.AddFilter("c", EQ, 3)
+			query.AddFilter("c", EQ, 3)
+		})
+		q.AddFilter("c", EQ, 3)
+		q.AddORFilters(func(query *Query) {
+			query.AddFilter("a", EQ, 1)
+			query.AddFilter("b", EQ, 2)
+		})
+		q.AddFilter("a", EQ, 1)
+		q.UniqueFilters()
+		assert.Equal(t, "c = ? AND (a = ? OR b = ?) AND a = ?", q.Where())
+		assert.Equal(t, []interface{}{3, 1, 2, 1}, q.Args())
+	})
+}
+
+func TestQuery_SortFilters(t *testing.T) {
+	indexed := map[string]bool{"id": true, "email": true}
+	byIndex := func(a, b *Filter) bool {
+		return indexed[a.Name] && !indexed[b.Name]
+	}
+
+	q := New().AddFilter("name", EQ, "tim")
+	q.AddORFilters(func(query *Query) {
+		query.AddFilter("phone", EQ, "123")
+		query.AddFilter("email", EQ, "tim@example.com")
+		query.AddFilter("nick", EQ, "tim")
+	})
+	q.AddFilter("id", GT, 10)
+	q.AddFilterRaw("deleted_at IS NULL")
+
+	q.SortFilters(byIndex)
+	assert.Equal(t, "(email = ? OR phone = ? OR nick = ?) AND id > ? AND name = ? AND deleted_at IS NULL", q.Where())
+	assert.Equal(t, []interface{}{"tim@example.com", "123", "tim", 10, "tim"}, q.Args())
+
+	q = New()
+	assert.Equal(t, "", q.SortFilters(byIndex).Where())
+}
+
+func TestQuery_GroupFiltersByTable(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil, "total:int": nil, "status": nil})
+	assert.NoError(t, q.SetUrlString("?id=1&total[gt]=100&status=new"))
+	assert.NoError(t, q.Parse())
+	q.ReplaceNames(Replacer{"id": "users.id", "total": "orders.total"})
+	q.AddFilterRaw("users.deleted_at IS NULL")
+
+	groups := q.GroupFiltersByTable()
+	assert.Len(t, groups, 3)
+	if assert.Len(t, groups["users"], 1) {
+		assert.Equal(t, "users.id", groups["users"][0].Name)
+	}
+	if assert.Len(t, groups["orders"], 1) {
+		assert.Equal(t, "orders.total", groups["orders"][0].Name)
+	}
+	assert.Len(t, groups[""], 2)
+
+	assert.Len(t, New().GroupFiltersByTable(), 0)
+}
+
+func TestQuery_ForTable(t *testing.T) {
+	q := New().AppendField("users.id", "users.name", "orders.total").
+		AddFilter("users.id", EQ, 1).
+		AddFilter("orders.total", GT, 100).
+		AddSortBy("orders.total", true).
+		AddSortBy("users.name", false)
+	q.AddORFilters(func(query *Query) {
+		query.AddFilter("users.name", ILIKE, "*tim*")
+		query.AddFilter("users.email", ILIKE, "*tim*")
+	})
+	q.AddORFilters(func(query *Query) {
+		query.AddFilter("users.name", EQ, "tim")
+		query.AddFilter("orders.status", EQ, "new")
+	})
+
+	assert.Equal(t, "SELECT users.id, users.name FROM users WHERE users.id = ? AND (users.name ILIKE ? OR users.email ILIKE ?) ORDER BY users.name", q.ForTable("users").SQL("users"))
+	assert.Equal(t, "SELECT orders.total FROM orders WHERE orders.total > ? ORDER BY orders.total DESC", q.ForTable("orders").SQL("orders"))
+	assert.Equal(t, "SELECT * FROM items", q.ForTable("items").SQL("items"))
+
+	// original query is not modified
+	assert.Len(t, q.Filters, 6)
+	assert.Len(t, q.Fields, 3)
+	assert.Len(t, q.Sorts, 2)
+}
+
+func TestQuery_AddFilterRawArgs(t *testing.T) {
+	q := New().
+		AddFilter("id", EQ, 1).
+		AddFilterRaw("created_at > NOW() - ? * INTERVAL '1 day'", 7).
+		AddFilterRaw("deleted_at IS NULL").
+		AddFilter("name", EQ, "tim")
+
+	assert.Equal(t, " WHERE id = ? AND created_at > NOW() - ? * INTERVAL '1 day' AND deleted_at IS NULL AND name = ?", q.WHERE())
+	assert.Equal(t, []interface{}{1, 7, "tim"}, q.Args())
+
+	where, args := q.WhereWithPlaceholderOffset(0)
+	assert.Equal(t, "id = $1 AND created_at > NOW() - $2 * INTERVAL '1 day' AND deleted_at IS NULL AND name = $3", where)
+	assert.Equal(t, []interface{}{1, 7, "tim"}, args)
+
+	// mismatched number of arguments is rejected instead of being dropped from WHERE
+	q = New().AddFilterRaw("tenant_id = ? AND x = ?", 1).AddFilter("b", EQ, 2)
+	assert.Equal(t, ErrBadFormat, errors.Cause(q.Error))
+	assert.Len(t, q.Filters, 1)
+	assert.Equal(t, " WHERE b = ?", q.WHERE())
+
+	// raw conditions with arguments are never dropped from WHERE and Args
+	q = New().AddFilterRaw("tenant_id = ? AND x = ?", 1, 2).AddFilter("b", EQ, 3)
+	assert.NoError(t, q.Error)
+	assert.Equal(t, " WHERE tenant_id = ? AND x = ? AND b = ?", q.WHERE())
+	assert.Equal(t, []interface{}{1, 2, 3}, q.Args())
+}
+
+func TestQuery_SetNullAsArg(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1).AddFilter("deleted_at", IS, NULL).AddFilter("email", NOT, NULL)
+	assert.Equal(t, " WHERE id = ? AND deleted_at IS NULL AND email IS NOT NULL", q.WHERE())
+	assert.Equal(t, []interface{}{1}, q.Args())
+
+	q.SetNullAsArg(true)
+	assert.Equal(t, " WHERE id = ? AND deleted_at IS ? AND email IS NOT ?", q.WHERE())
+	assert.Equal(t, []interface{}{1, NULL, NULL}, q.Args())
+	assert.True(t, q.Clone().nullAsArg)
+
+	where, args := q.WhereWithPlaceholderOffset(0)
+	assert.Equal(t, "id = $1 AND deleted_at IS $2 AND email IS NOT $3", where)
+	assert.Equal(t, []interface{}{1, NULL, NULL}, args)
+}
+
+func TestQuery_AddSubqueryFilter(t *testing.T) {
+	q := New().AddFilter("status", EQ, "new").
+		AddSubqueryFilter("user_id", "SELECT id FROM users WHERE org_id = ? AND active = ?", []interface{}{7, true}).
+		AddFilter("total", GT, 100)
+
+	assert.True(t, q.HaveFilter("user_id"))
+	assert.Equal(t, "SELECT * FROM orders WHERE status = ? AND user_id IN (SELECT id FROM users WHERE org_id = ? AND active = ?) AND total > ?", q.SQL("orders"))
+	assert.Equal(t, []interface{}{"new", 7, true, 100}, q.Args())
+
+	where, _ := q.WhereWithPlaceholderOffset(0)
+	assert.Equal(t, "status = $1 AND user_id IN (SELECT id FROM users WHERE org_id = $2 AND active = $3) AND total > $4", where)
+
+	q = New().AddSubqueryFilter("user_id", "SELECT id FROM admins", nil)
+	assert.Equal(t, " WHERE user_id IN (SELECT id FROM admins)", q.WHERE())
+	assert.Len(t, q.Args(), 0)
+}
+
+func TestQuery_RETURNING(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1)
+	assert.Equal(t, " RETURNING *", q.RETURNING())
+	assert.Equal(t, " RETURNING id, email", q.RETURNING("id", "email"))
+	assert.Equal(t, "DELETE FROM users WHERE id = ? RETURNING id", "DELETE FROM users"+q.WHERE()+q.RETURNING("id"))
+}
+
+func TestQuery_UPDATESet(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1)
+
+	sql, args, err := q.UPDATESet("users", map[string]interface{}{
+		"status": "active",
+		"name":   "tim",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET name = ?, status = ? WHERE id = ?", sql)
+	assert.Equal(t, []interface{}{"tim", "active", 1}, args)
+
+	q.CTE("banned", "SELECT id FROM bans WHERE reason = ?", []interface{}{"spam"})
+	sql, args, err = q.UPDATESet("users", map[string]interface{}{"status": "banned"})
+	assert.NoError(t, err)
+	assert.Equal(t, "WITH banned AS (SELECT id FROM bans WHERE reason = ?) UPDATE users SET status = ? WHERE id = ?", sql)
+	assert.Equal(t, []interface{}{"spam", "banned", 1}, args)
+
+	sql, args, err = New().UPDATESet("users", map[string]interface{}{"status": "new"})
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET status = ?", sql)
+	assert.Equal(t, []interface{}{"new"}, args)
+
+	for _, setFields := range []map[string]interface{}{nil, {}} {
+		sql, args, err = q.UPDATESet("users", setFields)
+		assert.Equal(t, ErrRequired, err)
+		assert.Equal(t, "", sql)
+		assert.Nil(t, args)
+	}
+}
+
+func TestQuery_IncrementSQL(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1)
+
+	sql, args := q.IncrementSQL("posts", "views", 1)
+	assert.Equal(t, "UPDATE posts SET views = views + ? WHERE id = ?", sql)
+	assert.Equal(t, []interface{}{1, 1}, args)
+
+	sql, args = New().IncrementSQL("posts", "stock", -5)
+	assert.Equal(t, "UPDATE posts SET stock = stock + ?", sql)
+	assert.Equal(t, []interface{}{-5}, args)
+}
+
+func TestQuery_InsertSQL(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1)
+
+	sql, args, err := q.InsertSQL("users", map[string]interface{}{
+		"name":  "tim",
+		"email": "tim@example.com",
+		"age":   30,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (age, email, name) VALUES (?, ?, ?)", sql)
+	assert.Equal(t, []interface{}{30, "tim@example.com", "tim"}, args)
+
+	q.CTE("org", "SELECT id FROM orgs WHERE name = ?", []interface{}{"acme"})
+	sql, args, err = q.InsertSQL("users", map[string]interface{}{"name": "tim"})
+	assert.NoError(t, err)
+	assert.Equal(t, "WITH org AS (SELECT id FROM orgs WHERE name = ?) INSERT INTO users (name) VALUES (?)", sql)
+	assert.Equal(t, []interface{}{"acme", "tim"}, args)
+
+	sql, args, err = q.InsertSQL("users", map[string]interface{}{})
+	assert.Equal(t, ErrRequired, err)
+	assert.Equal(t, "", sql)
+	assert.Nil(t, args)
+}
+
+func TestQuery_UpsertSQL(t *testing.T) {
+	q := New()
+	data := map[string]interface{}{"email": "tim@example.com", "name": "tim", "age": 30}
+
+	sql, args, err := q.UpsertSQL("users", data, []string{"email"})
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (age, email, name) VALUES (?, ?, ?) ON CONFLICT (email) DO UPDATE SET age = EXCLUDED.age, name = EXCLUDED.name", sql)
+	assert.Equal(t, []interface{}{30, "tim@example.com", "tim"}, args)
+
+	sql, _, err = q.UpsertSQL("users", map[string]interface{}{"email": "tim@example.com"}, []string{"email"})
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (email) VALUES (?) ON CONFLICT (email) DO NOTHING", sql)
+
+	sql, _, err = q.UpsertSQL("users", data, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (age, email, name) VALUES (?, ?, ?) ON CONFLICT DO NOTHING", sql)
+
+	_, _, err = q.UpsertSQL("users", nil, []string{"email"})
+	assert.Equal(t, ErrRequired, err)
+}
+
+func TestQuery_UpsertSQLMySQL(t *testing.T) {
+	q := New()
+	data := map[string]interface{}{"email": "tim@example.com", "name": "tim", "age": 30}
+
+	sql, args, err := q.UpsertSQLMySQL("users", data, []string{"email"})
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (age, email, name) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE age = VALUES(age), name = VALUES(name)", sql)
+	assert.Equal(t, []interface{}{30, "tim@example.com", "tim"}, args)
+
+	sql, _, err = q.UpsertSQLMySQL("users", map[string]interface{}{"email": "tim@example.com"}, []string{"email"})
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO users (email) VALUES (?) ON DUPLICATE KEY UPDATE email = email", sql)
+
+	_, _, err = q.UpsertSQLMySQL("users", nil, []string{"email"})
+	assert.Equal(t, ErrRequired, err)
+}
+
+func TestQuery_SelectUpdateDeleteSQL(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1).SetLimit(10)
+	assert.Equal(t, q.SQL("users"), q.SelectSQL("users"))
+
+	sql, args := q.UpdateWhereSQL("users", "status = ?, updated_at = NOW()", []interface{}{"active"})
+	assert.Equal(t, "UPDATE users SET status = ?, updated_at = NOW() WHERE id = ?", sql)
+	assert.Equal(t, []interface{}{"active", 1}, args)
+
+	sql, args, err := q.DeleteWhereSQL("users")
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE FROM users WHERE id = ?", sql)
+	assert.Equal(t, []interface{}{1}, args)
+
+	sql, args, err = New().DeleteWhereSQL("users")
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE FROM users", sql)
+	assert.Empty(t, args)
+
+	sql, args, err = New().SetRequireFilterForDelete(true).DeleteWhereSQL("users")
+	assert.Equal(t, ErrRequired, err)
+	assert.Equal(t, "", sql)
+	assert.Nil(t, args)
+}
+
+func TestQuery_DebugSQL(t *testing.T) {
+	q := New().
+		AddFilter("id", IN, []int{1, 2}).
+		AddFilter("name", EQ, "o'neil").
+		AddFilter("active", EQ, true).
+		AddFilter("deleted_at", IS, NULL).
+		AddFilter("note", EQ, "what?").
+		SetLimit(10)
+
+	assert.Equal(t, "SELECT * FROM users WHERE id IN (1, 2) AND name = 'o''neil' AND active = true AND deleted_at IS NULL AND note = 'what?' LIMIT 10", q.DebugSQL("users"))
+	assert.Equal(t, "SELECT * FROM users", New().DebugSQL("users"))
+
+	// literal `?` of raw condition without arguments isn't a placeholder
+	q = New().AddFilterRaw("data ? 'k'").AddFilter("id", EQ, 5)
+	assert.Equal(t, "SELECT * FROM users WHERE data ? 'k' AND id = 5", q.DebugSQL("users"))
+
+	q = New().AddFilter("deleted_at", IS, NULL).AddFilter("id", EQ, 5).SetNullAsArg(true)
+	assert.Equal(t, "SELECT * FROM users WHERE deleted_at IS NULL AND id = 5", q.DebugSQL("users"))
+	assert.Equal(t, "SELECT * FROM users WHERE deleted_at IS ? AND id = ?", q.SQL("users"))
+
+	q = New().CTE("active", "SELECT id FROM users WHERE active = ?", []interface{}{true}).
+		AddFilterRaw("id IN (SELECT id FROM active)").
+		AddFilter("name", EQ, "what?").
+		AddFilter("id", GT, 1)
+	assert.Equal(t, "WITH active AS (SELECT id FROM users WHERE active = true) SELECT * FROM users WHERE id IN (SELECT id FROM active) AND name = 'what?' AND id > 1", q.DebugSQL("users"))
+}
+
+func TestQuery_StoredProcSQL(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1).AddFilter("email", EQ, "tim@example.com").AddSortBy("id", true).SetLimit(10)
+
+	sql, args := q.StoredProcSQL("search_users")
+	assert.Equal(t, "CALL search_users(?, ?, ?, ?, ?)", sql)
+	assert.Equal(t, []interface{}{1, "tim@example.com", "id DESC", 10, 0}, args)
+
+	sql, args = New().StoredProcSQL("all_users")
+	assert.Equal(t, "CALL all_users(?, ?, ?)", sql)
+	assert.Equal(t, []interface{}{"", 0, 0}, args)
+}
+
+func TestQuery_StoredFuncSQL(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1).AddFilter("email", EQ, "tim@example.com").AddSortBy("id", true).SetLimit(10)
+
+	sql, args := q.StoredFuncSQL("search_users")
+	assert.Equal(t, "SELECT * FROM search_users($1, $2, $3, $4, $5)", sql)
+	_, procArgs := q.StoredProcSQL("search_users")
+	assert.Equal(t, procArgs, args)
+
+	sql, args = New().StoredFuncSQL("all_users")
+	assert.Equal(t, "SELECT * FROM all_users($1, $2, $3)", sql)
+	assert.Equal(t, []interface{}{"", 0, 0}, args)
+}
+
+func TestQuery_DELETE(t *testing.T) {
+	q := New().AddFilter("id", EQ, 1)
+	assert.Equal(t, "DELETE FROM users WHERE id = ?", q.DELETE("users"))
+	assert.Equal(t, []interface{}{1}, q.DELETEArgs())
+
+	q = New()
+	assert.Equal(t, "DELETE FROM users", q.DELETE("users"))
+	assert.NoError(t, q.Error)
+
+	q.SetRequireFilterForDelete(true)
+	assert.Equal(t, "", q.DELETE("users"))
+	assert.NoError(t, q.Error)
+	assert.True(t, q.Clone().requireFilterForDelete)
+
+	q.AddFilter("id", IN, []int{1, 2})
+	assert.Equal(t, "DELETE FROM users WHERE id IN (?, ?)", q.DELETE("users"))
+
+	// DeleteWhereSQL reports an error whenever DELETE returns empty string
+	q = New().SetRequireFilterForDelete(true)
+	sql, _, err := q.DeleteWhereSQL("users")
+	assert.Equal(t, q.DELETE("users"), sql)
+	assert.Equal(t, ErrRequired, err)
+}
+
+func TestQuery_NamedFilter(t *testing.T) {
+	q := New().AddFilter("id", GT, 1).
+		NamedFilter("onlyActive", &Filter{Name: "status", Method: EQ, Value: "active"})
+	assert.Equal(t, "id > ? AND status = ?", q.Where())
+
+	f, err := q.GetNamedFilter("onlyActive")
+	assert.NoError(t, err)
+	assert.Equal(t, "status", f.Name)
+
+	_, err = q.GetNamedFilter("unknown")
+	assert.Equal(t, ErrFilterNotFound, err)
+
+	qc := q.Clone()
+	f, err = qc.GetNamedFilter("onlyActive")
+	assert.NoError(t, err)
+	assert.Equal(t, "status", f.Name)
+
+	assert.NoError(t, q.RemoveFilter("onlyActive"))
+	assert.Equal(t, "id > ?", q.Where())
+	_, err = q.GetNamedFilter("onlyActive")
+	assert.Equal(t, ErrFilterNotFound, err)
+
+	// alias of the filter removed by name is removed too
+	assert.NoError(t, qc.RemoveFilter("status"))
+	_, err = qc.GetNamedFilter("onlyActive")
+	assert.Equal(t, ErrFilterNotFound, err)
+	assert.Equal(t, ErrFilterNotFound, qc.RemoveFilter("onlyActive"))
+	assert.Equal(t, "id > ?", qc.Where())
+
+	// Parse replaces filters so aliases are removed too
+	URL, _ := url.Parse("?id=1")
+	q = NewQV(URL.Query(), Validations{"id:int": nil}).
+		NamedFilter("onlyActive", &Filter{Name: "status", Method: EQ, Value: "active"})
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, "id = ?", q.Where())
+	_, err = q.GetNamedFilter("onlyActive")
+	assert.Equal(t, ErrFilterNotFound, err)
+}
+
+func TestQuery_Apply(t *testing.T) {
+	ownedBy := func(userID int) func(*Query) {
+		return func(q *Query) {
+			q.AddFilter("user_id", EQ, userID)
+		}
+	}
+	onlyActive := func(q *Query) {
+		q.AddFilter("status", EQ, "active")
+	}
+
+	q := New().Apply(ownedBy(7), onlyActive).Apply()
+	assert.Equal(t, "user_id = ? AND status = ?", q.Where())
+	assert.Equal(t, []interface{}{7, "active"}, q.Args())
+}
+
+func TestQuery_Tap(t *testing.T) {
+	var seen string
+	q := New().AddFilter("id", EQ, 1).Tap(func(q *Query) {
+		seen = q.Where()
+		q.AddFilter("status", EQ, "active").AddSortBy("id", true).SetLimit(10)
+	})
+
+	assert.Equal(t, "id = ?", seen)
+	assert.Equal(t, "SELECT * FROM users WHERE id = ?", q.SQL("users"))
+}
+
+func TestQuery_SetOnAfterParse(t *testing.T) {
+	var (
+		calls   int
+		filters int
+		lastErr error
+	)
+	q := New().SetValidations(Validations{"id:int": nil}).SetOnAfterParse(func(q *Query, err error) {
+		calls++
+		filters += len(q.Filters)
+		lastErr = err
+	})
+
+	assert.NoError(t, q.SetUrlString("?id[gte]=1&id[lte]=10"))
+	assert.NoError(t, q.Parse())
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 2, filters)
+	assert.NoError(t, lastErr)
+
+	assert.NoError(t, q.SetUrlString("?id=one"))
+	assert.Error(t, q.Clone().Parse())
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, ErrBadFormat, errors.Cause(lastErr))
+}
+
+func TestQuery_Hash(t *testing.T) {
+	v := Validations{"fields": In("id", "name"), "sort": In("id"), "id:int": nil, "name": nil, "email": nil}
+
+	q1, err := NewParse(url.Values{"id[gt]": {"1"}, "name[like]": {"tim|email[like]=tim"}, "limit": {"10"}}, v)
+	assert.NoError(t, err)
+	q2 := New().SetLimit(10)
+	q2.AddORFilters(func(query *Query) {
+		query.AddFilter("email", LIKE, "tim")
+		query.AddFilter("name", LIKE, "tim")
+	})
+	q2.AddFilter("id", GT, 1)
+
+	assert.Len(t, q1.Hash(), 64)
+	assert.Equal(t, q1.Hash(), q2.Hash())
+	assert.Equal(t, q1.HashFiltersOnly(), q2.HashFiltersOnly())
+
+	q2.SetOffset(10)
+	assert.NotEqual(t, q1.Hash(), q2.Hash())
+	assert.Equal(t, q1.HashFiltersOnly(), q2.HashFiltersOnly())
+
+	// type of value matters
+	assert.NotEqual(t, New().AddFilter("id", EQ, 1).Hash(), New().AddFilter("id", EQ, "1").Hash())
+	// OR statement differs from filters joined by AND
+	q3 := New().SetLimit(10).AddFilter("id", GT, 1).AddFilter("email", LIKE, "tim").AddFilter("name", LIKE, "tim")
+	assert.NotEqual(t, q1.Hash(), q3.Hash())
+	// sorts and fields are ordered
+	assert.NotEqual(t, New().AddSortBy("id", false).Hash(), New().AddSortBy("id", true).Hash())
+	assert.NotEqual(t, New().AppendField("id", "name").Hash(), New().AppendField("name", "id").Hash())
+}
+
+func TestQuery_ToQueryString(t *testing.T) {
+	validations := Validations{
+		"fields": In("id", "name"),
+		"sort":   In("id", "name"),
+		"id:int": nil,
+		"email":  nil,
+		"name":   nil,
+		"s":      nil,
+	}
+
+	URL, _ := url.Parse("/?fields=id,name&sort=-id,name&limit=10&offset=20&id[in]=1,2&email[like]=*tim*|name[like]=*tim*&s[is]=null")
+	q, err := NewParse(URL.Query(), validations)
+	assert.NoError(t, err)
+	q.AddFilterRaw("deleted_at IS NULL")
+
+	str := q.ToQueryString()
+	assert.True(t, strings.HasPrefix(str, "fields=id,name&sort=-id,name&limit=10&offset=20&"), str)
+	assert.Contains(t, str, "&id[in]=1,2")
+	assert.Contains(t, str, "&email[like]=*tim*|name[like]=*tim*")
+	assert.Contains(t, str, "&s[is]=NULL")
+	assert.NotContains(t, str, "deleted_at")
+
+	// round-trip
+	q.RemoveFilter("deleted_at IS NULL")
+	q2 := New().SetValidations(validations)
+	assert.NoError(t, q2.SetUrlString("/?"+str))
+	assert.NoError(t, q2.Parse())
+	assert.Equal(t, q.Hash(), q2.Hash())
+
+	q = New().AddFilter("id", EQ, 1)
+	q2 = New().SetValidations(validations)
+	assert.NoError(t, q2.SetUrlString("/?"+q.ToQueryString()))
+	assert.NoError(t, q2.Parse())
+	assert.True(t, q.Equals(q2))
+
+	assert.Equal(t, "", New().ToQueryString())
+}
+
+func TestQuery_ParseString(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil})
+	assert.NoError(t, q.ParseString("http://localhost/?id[gt]=1&limit=5"))
+	assert.Equal(t, " WHERE id > ?", q.WHERE())
+	assert.Equal(t, 5, q.Limit)
+
+	assert.Error(t, q.ParseString("://localhost"))
+	assert.Equal(t, ErrBadFormat, errors.Cause(q.ParseString("http://localhost/?id=one")))
+}
+
+func TestQuery_ValidationErrorOrder(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": Min(1)})
+
+	// the first invalid value is reported whether it fails validation or conversion
+	err := q.ParseValues(url.Values{"id": {"0", "one"}})
+	assert.Equal(t, ErrNotInScope, errors.Cause(err))
+	assert.Empty(t, q.Filters)
+
+	err = q.ParseValues(url.Values{"id": {"one", "0"}})
+	assert.Equal(t, ErrBadFormat, errors.Cause(err))
+
+	err = q.ParseValues(url.Values{"id": {"2|id=0|id=one"}})
+	assert.Equal(t, ErrNotInScope, errors.Cause(err))
+	assert.Len(t, q.Filters, 1)
+}
+
+func TestQuery_ParseValues(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil})
+	assert.NoError(t, q.ParseValues(url.Values{"id[gt]": {"1"}}))
+	assert.Equal(t, " WHERE id > ?", q.WHERE())
+	assert.Equal(t, ErrBadFormat, errors.Cause(q.ParseValues(url.Values{"id": {"one"}})))
+
+	r := httptest.NewRequest(http.MethodGet, "/users?id[in]=1,2&limit=5", nil)
+	assert.NoError(t, q.ParseRequest(r))
+	assert.Equal(t, " WHERE id IN (?, ?)", q.WHERE())
+	assert.Equal(t, []interface{}{1, 2}, q.Args())
+	assert.Equal(t, 5, q.Limit)
+}
+
+func TestNewFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users?id=1&sort=-id", nil)
+	q, err := NewFromRequest(r, Validations{"id:int": nil, "sort": In("id")})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE id = ? ORDER BY id DESC", q.SQL("users"))
+
+	r = httptest.NewRequest(http.MethodGet, "/users?id=one", nil)
+	_, err = NewFromRequest(r, Validations{"id:int": nil})
+	assert.Equal(t, ErrBadFormat, errors.Cause(err))
+}
+
+func TestQuery_Fingerprint(t *testing.T) {
+	assert.Equal(t, "", New().Fingerprint())
+
+	q := New().AppendField("id", "name").AddSortBy("created_at", true).SetLimit(20).SetOffset(40).
+		AddFilter("status", IN, []string{"new", "done"}).
+		AddFilter("id", GT, 10)
+	q.AddORFilters(func(query *Query) {
+		query.AddFilter("name", LIKE, "*tim*")
+		query.AddFilter("email", LIKE, "*tim*")
+	})
+	q.AddFilterRaw("secret = 'value'")
+
+	fp := q.Fingerprint()
+	assert.Equal(t, "fields=id,name;sort=-created_at;limit=20;offset=40;where=(email:LIKE|name:LIKE),id:GT,raw,status:IN", fp)
+	assert.NotContains(t, fp, "tim")
+	assert.NotContains(t, fp, "value")
+}
+
+func TestQuery_CloneFilters(t *testing.T) {
+	q := New().AddFilter("id", IN, []int{1, 2}).AddFilter("name", EQ, "tim").
+		NamedFilter("active", &Filter{Name: "status", Method: EQ, Value: "active"})
+
+	qc := q.Clone()
+	QueryEqual(t, q, qc)
+
+	qc.Filters[0].Value.([]int)[0] = 100
+	qc.Filters[1].Value = "bob"
+	qc.Filters[1].Name = "nick"
+	assert.Equal(t, []int{1, 2}, q.Filters[0].Value)
+	assert.Equal(t, "tim", q.Filters[1].Value)
+	assert.Equal(t, "name", q.Filters[1].Name)
+
+	// named filter of copy points to copied filter
+	f, err := qc.GetNamedFilter("active")
+	assert.NoError(t, err)
+	assert.True(t, f == qc.Filters[2])
+	assert.False(t, f == q.Filters[2])
+	assert.NoError(t, qc.RemoveFilter("active"))
+	assert.Len(t, qc.Filters, 2)
+	assert.Len(t, q.Filters, 3)
+}
+
+func TestQuery_IsEmpty(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil})
+	assert.True(t, q.IsEmpty())
+
+	assert.NoError(t, q.SetUrlString("?"))
+	assert.NoError(t, q.Parse())
+	assert.True(t, q.IsEmpty())
+
+	assert.NoError(t, q.SetUrlString("?id=1"))
+	assert.NoError(t, q.Parse())
+	assert.False(t, q.IsEmpty())
+
+	assert.False(t, New().SetLimit(10).IsEmpty())
+	assert.False(t, New().SetOffset(10).IsEmpty())
+	assert.False(t, New().AddField("id").IsEmpty())
+	assert.False(t, New().AddSortBy("id", false).IsEmpty())
+}
+
+func TestQuery_Equals(t *testing.T) {
+	v := Validations{"fields": In("id", "name"), "sort": In("id"), "id:int": nil, "name": nil}
+
+	q1, err := NewParse(url.Values{"fields": {"id,name"}, "sort": {"-id"}, "limit": {"10"}, "id[in]": {"1,2"}}, v)
+	assert.NoError(t, err)
+	q2 := New().AppendField("id", "name").AddSortBy("id", true).SetLimit(10).AddFilter("id", IN, []int{1, 2})
+	q2.SetDelimiterIN(";")
+
+	assert.True(t, q1.Equals(q2))
+	assert.True(t, q1.Equals(q1.Clone()))
+	assert.False(t, q1.Equals(nil))
+	assert.True(t, New().Equals(New()))
+
+	assert.False(t, q1.Equals(q2.Clone().SetOffset(10)))
+	assert.False(t, q1.Equals(q2.Clone().AddField("email")))
+	assert.False(t, q1.Equals(q2.Clone().AddSortBy("name", false)))
+	assert.False(t, q1.Equals(q2.Clone().AddFilter("name", EQ, "tim")))
+
+	q3 := q2.Clone()
+	q3.Filters[0].Value = []int{1, 3}
+	assert.False(t, q1.Equals(q3))
+
+	q4 := New().AddFilter("a", EQ, 1).AddFilter("b", EQ, 2)
+	q5 := New().AddORFilters(func(query *Query) {
+		query.AddFilter("a", EQ, 1)
+		query.AddFilter("b", EQ, 2)
+	})
+	assert.False(t, q4.Equals(q5))
+
+	q6 := New().AddSubqueryFilter("id", "SELECT id FROM a", nil)
+	q7 := New().AddSubqueryFilter("id", "SELECT id FROM b", nil)
+	assert.False(t, q6.Equals(q7))
+}
+
+func TestQuery_Diff(t *testing.T) {
+	q1 := New().AppendField("id").AddSortBy("id", false).SetLimit(10).
+		AddFilter("status", EQ, "new").
+		AddFilter("id", GT, 1).
+		AddFilter("name", LIKE, "*tim*")
+	q2 := New().AppendField("id").AddSortBy("id", false).SetLimit(10).
+		AddFilter("id", GT, 5).
+		AddFilter("status", EQ, "new").
+		AddFilter("email", LIKE, "*tim*")
+
+	diff := q1.Diff(q2)
+	assert.False(t, diff.SortChanged)
+	assert.False(t, diff.PaginationChanged)
+	assert.False(t, diff.FieldsChanged)
+	if assert.Len(t, diff.ChangedFilters, 1) {
+		assert.Equal(t, 1, diff.ChangedFilters[0].Old.Value)
+		assert.Equal(t, 5, diff.ChangedFilters[0].New.Value)
+	}
+	if assert.Len(t, diff.RemovedFilters, 1) {
+		assert.Equal(t, "name", diff.RemovedFilters[0].Name)
+	}
+	if assert.Len(t, diff.AddedFilters, 1) {
+		assert.Equal(t, "email", diff.AddedFilters[0].Name)
+	}
+
+	diff = q1.Diff(q1.Clone().SetOffset(10).AddField("name").AddSortBy("name", true))
+	assert.True(t, diff.SortChanged)
+	assert.True(t, diff.PaginationChanged)
+	assert.True(t, diff.FieldsChanged)
+	assert.Len(t, diff.AddedFilters, 0)
+	assert.Len(t, diff.RemovedFilters, 0)
+	assert.Len(t, diff.ChangedFilters, 0)
+
+	assert.Equal(t, QueryDiff{}, New().Diff(New()))
+	assert.Len(t, q1.Diff(nil).RemovedFilters, 3)
+}
+
+func TestErrorsIs(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil, "limit:required": nil})
+
+	assert.NoError(t, q.SetUrlString("?id=1"))
+	err := q.Parse()
+	assert.True(t, stderrors.Is(err, ErrRequired))
+	assert.Equal(t, ErrRequired, errors.Cause(err))
+
+	assert.NoError(t, q.SetUrlString("?limit=10&id=one"))
+	err = q.Parse()
+	assert.True(t, stderrors.Is(err, ErrBadFormat))
+	assert.False(t, stderrors.Is(err, ErrRequired))
+	assert.Equal(t, ErrBadFormat, errors.Cause(err))
+
+	assert.NoError(t, q.SetUrlString("?limit=10&name=tim"))
+	var e *Error
+	assert.True(t, stderrors.As(q.Parse(), &e))
+	assert.Equal(t, ErrFilterNotFound, e)
+}
+
+func TestQuery_SetKeyPattern(t *testing.T) {
+	q := New().SetValidations(Validations{"id:int": nil, "user.name": nil, "utm-source": nil})
+
+	cases := []struct {
+		query url.Values
+		err   string
+	}{
+		{query: url.Values{"id; DROP TABLE users": {"1"}}, err: "id; DROP TABLE users: invalid character"},
+		{query: url.Values{"1id": {"1"}}, err: "1id: invalid character"},
+		{query: url.Values{"id[eq]": {"1|id; DROP TABLE users=2"}}, err: "id; DROP TABLE users: invalid character"},
+		{query: url.Values{"utm-source": {"mail"}}, err: "utm-source: invalid character"},
+		{query: url.Values{"id[eq]": {"1"}, "user.name": {"tim"}}},
+	}
+	for _, c := range cases {
+		err := q.SetUrlQuery(c.query).Parse()
+		if len(c.err) > 0 {
+			assert.EqualError(t, err, c.err)
+			assert.Equal(t, ErrInvalidCharacter, errors.Cause(err))
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	// invalid keys are unknown filters
+	q.IgnoreUnknownFilters(true)
+	assert.NoError(t, q.SetUrlQuery(url.Values{"id; DROP TABLE users": {"1"}}).Parse())
+	assert.Len(t, q.Filters, 0)
+	q.IgnoreUnknownFilters(false)
+
+	q.SetKeyPattern(`^[a-z_-]+(\[.*\])?$`)
+	assert.NoError(t, q.Error)
+	assert.NoError(t, q.SetUrlQuery(url.Values{"utm-source": {"mail"}}).Parse())
+	assert.Equal(t, " WHERE utm-source = ?", q.WHERE())
+	assert.NoError(t, q.Clone().Parse())
+
+	q.SetKeyPattern(`[`)
+	assert.Equal(t, ErrBadFormat, errors.Cause(q.Error))
+	// invalid pattern isn't ignored by Parse
+	assert.Equal(t, ErrBadFormat, errors.Cause(q.Parse()))
+	assert.Equal(t, ErrBadFormat, errors.Cause(q.Clone().Parse()))
+	assert.Len(t, q.Filters, 0)
+
+	q.SetKeyPattern(`^[a-z_-]+(\[.*\])?$`)
+	assert.NoError(t, q.Parse())
+}
+
+func TestQuery_cleanFilters(t *testing.T) {
+	values := url.Values{}
+	for i := 0; i < 300; i++ {
+		values.Add(fmt.Sprintf("f%d[in]", i), "a,b,c,d,e,f,g,h")
+	}
+	validations := Validations{}
+	for key := range values {
+		validations[key[:strings.Index(key, "[")]] = nil
+	}
+
+	q := NewQV(values, validations)
+	assert.NoError(t, q.Parse())
+	old := q.Filters
+
+	assert.NoError(t, q.Parse())
+	assert.Len(t, q.Filters, 300)
+	// previous filters are released
+	for i := range old {
+		assert.Nil(t, old[i])
+	}
+
+	// filters don't pile up with every Parse
+	capacity := cap(q.Filters)
+	for i := 0; i < 20; i++ {
+		old = q.Filters
+		assert.NoError(t, q.Parse())
+		assert.Nil(t, old[0])
+		assert.Equal(t, capacity, cap(q.Filters))
+	}
+	assert.Len(t, q.Filters, 300)
+}
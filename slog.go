@@ -0,0 +1,40 @@
+//go:build go1.21
+// +build go1.21
+
+package rqp
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SetLogger sets structured logger for Parse.
+// Every parsed filter and total time of parsing are logged at DEBUG level,
+// error of parsing is logged at WARN level.
+// Logging is disabled by default or when logger is nil.
+func (q *Query) SetLogger(logger *slog.Logger) *Query {
+	if logger == nil {
+		q.logParse = nil
+		return q
+	}
+
+	q.logParse = func(q *Query, err error, took time.Duration) {
+		for _, f := range q.Filters {
+			logger.Debug("rqp: filter parsed",
+				slog.String("name", f.Name),
+				slog.String("method", string(f.Method)),
+				slog.String("type", fmt.Sprintf("%T", f.Value)),
+			)
+		}
+		if err != nil {
+			logger.Warn("rqp: parse failed", slog.String("error", err.Error()))
+		}
+		logger.Debug("rqp: parse finished",
+			slog.Int("filters", len(q.Filters)),
+			slog.Duration("took", took),
+		)
+	}
+
+	return q
+}
@@ -0,0 +1,35 @@
+//go:build go1.21
+// +build go1.21
+
+package rqp
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery_SetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	q := New().SetValidations(Validations{"id:int": nil}).SetLogger(logger)
+
+	assert.NoError(t, q.SetUrlString("?id[gt]=1"))
+	assert.NoError(t, q.Parse())
+	assert.Contains(t, buf.String(), `level=DEBUG msg="rqp: filter parsed" name=id method=GT type=int`)
+	assert.Contains(t, buf.String(), `level=DEBUG msg="rqp: parse finished" filters=1`)
+	assert.NotContains(t, buf.String(), "level=WARN")
+
+	buf.Reset()
+	assert.NoError(t, q.SetUrlString("?id=one"))
+	assert.Error(t, q.Parse())
+	assert.Contains(t, buf.String(), `level=WARN msg="rqp: parse failed" error="id: bad format"`)
+
+	buf.Reset()
+	q.SetLogger(nil)
+	assert.Error(t, q.Parse())
+	assert.Empty(t, buf.String())
+}
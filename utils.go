@@ -1,6 +1,14 @@
 package rqp
 
-import "strings"
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
 
 func cleanSliceString(list []string) []string {
 	var clean []string
@@ -21,3 +29,114 @@ func stringInSlice(a string, list []string) bool {
 	}
 	return false
 }
+
+// tableOfName returns table part of qualified name (eg. "users" for "users.id")
+// or empty string if name isn't qualified
+func tableOfName(name string) string {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return name[:i]
+	}
+	return ""
+}
+
+// hashString returns SHA-256 hash of s in hex
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// equalStrings returns true if slices have the same elements in the same order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalSorts returns true if slices have the same sorts in the same order
+func equalSorts(a, b []Sort) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedKeys returns keys of m ordered alphabetically
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// placeholders returns n placeholders separated by comma, eg. "?, ?, ?"
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+// numberPlaceholders replaces placeholders "?" of s by PostgreSQL-style
+// numbered placeholders starting from argNum and returns next number
+func numberPlaceholders(s string, argNum int) (string, int) {
+	for strings.Contains(s, "?") {
+		s = strings.Replace(s, "?", fmt.Sprintf("$%d", argNum), 1)
+		argNum++
+	}
+	return s, argNum
+}
+
+// inlineArgs replaces placeholders of sql by arguments in order for DebugSQL
+func inlineArgs(sql string, args []interface{}) string {
+	var b strings.Builder
+	for _, arg := range args {
+		i := strings.Index(sql, "?")
+		if i == -1 {
+			break
+		}
+		b.WriteString(sql[:i])
+		b.WriteString(debugValue(arg))
+		sql = sql[i+1:]
+	}
+	b.WriteString(sql)
+
+	return b.String()
+}
+
+// debugValue returns value as SQL literal for DebugSQL
+func debugValue(value interface{}) string {
+	if v, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = v.Value(); err != nil {
+			return "?"
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return NULL
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	case []byte:
+		return "'" + strings.Replace(string(v), "'", "''", -1) + "'"
+	case time.Time:
+		return "'" + v.Format(time.RFC3339Nano) + "'"
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	default:
+		return "'" + strings.Replace(fmt.Sprint(v), "'", "''", -1) + "'"
+	}
+}
@@ -1,8 +1,11 @@
 package rqp
 
 import (
-	"github.com/stretchr/testify/assert"
+	"database/sql"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_stringInSlice(t *testing.T) {
@@ -10,3 +13,27 @@ func Test_stringInSlice(t *testing.T) {
 		assert.Equal(t, false, stringInSlice("", nil))
 	})
 }
+
+func Test_tableOfName(t *testing.T) {
+	assert.Equal(t, "", tableOfName("id"))
+	assert.Equal(t, "users", tableOfName("users.id"))
+	assert.Equal(t, "public.users", tableOfName("public.users.id"))
+}
+
+func Test_placeholders(t *testing.T) {
+	assert.Equal(t, "", placeholders(0))
+	assert.Equal(t, "?", placeholders(1))
+	assert.Equal(t, "?, ?, ?", placeholders(3))
+}
+
+func Test_debugValue(t *testing.T) {
+	assert.Equal(t, "NULL", debugValue(nil))
+	assert.Equal(t, "'it''s'", debugValue("it's"))
+	assert.Equal(t, "'abc'", debugValue([]byte("abc")))
+	assert.Equal(t, "42", debugValue(42))
+	assert.Equal(t, "1.5", debugValue(1.5))
+	assert.Equal(t, "false", debugValue(false))
+	assert.Equal(t, "'2020-10-02T10:00:00Z'", debugValue(time.Date(2020, 10, 2, 10, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "NULL", debugValue(sql.NullString{}))
+	assert.Equal(t, "'x'", debugValue(sql.NullString{String: "x", Valid: true}))
+}
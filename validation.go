@@ -1,6 +1,15 @@
 package rqp
 
 import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
@@ -24,8 +33,18 @@ func Multi(values ...ValidationFunc) ValidationFunc {
 	}
 }
 
-// In validation if values contatin value
+// In validation if values contatin value.
+// Value is converted to the type of values before comparison: integers are
+// converted to other numeric types and strings are parsed as float, time.Time
+// (RFC3339) or uuid.UUID, so In(int64(1), int64(2)) works for ":int" filters.
+// All values must be of the same type, otherwise In panics.
 func In(values ...interface{}) ValidationFunc {
+	for _, v := range values {
+		if reflect.TypeOf(v) != reflect.TypeOf(values[0]) {
+			panic(fmt.Sprintf("rqp: In: mixed types %T and %T", values[0], v))
+		}
+	}
+
 	return func(value interface{}) error {
 
 		var (
@@ -33,8 +52,12 @@ func In(values ...interface{}) ValidationFunc {
 			in bool = false
 		)
 
+		if len(values) > 0 {
+			value = castTo(value, values[0])
+		}
+
 		for _, v = range values {
-			if v == value {
+			if equalValues(v, value) {
 				in = true
 				break
 			}
@@ -48,6 +71,89 @@ func In(values ...interface{}) ValidationFunc {
 	}
 }
 
+// castTo converts value to the type of sample if it's possible.
+// Returns value as is if it can't be converted.
+func castTo(value, sample interface{}) interface{} {
+	switch sample.(type) {
+	case time.Time:
+		if s, ok := value.(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t
+			}
+		}
+		return value
+	case uuid.UUID:
+		if s, ok := value.(string); ok {
+			if id, err := uuid.Parse(s); err == nil {
+				return id
+			}
+		}
+		return value
+	}
+
+	rv, st := reflect.ValueOf(value), reflect.TypeOf(sample)
+	if !rv.IsValid() || rv.Type() == st {
+		return value
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch st.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			c := rv.Convert(st)
+			// skip overflowed values
+			if c.Convert(rv.Type()).Int() == rv.Int() && (rv.Int() >= 0 || !isUnsigned(st)) {
+				return c.Interface()
+			}
+		}
+	case reflect.String:
+		switch st.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if f, err := strconv.ParseFloat(rv.String(), st.Bits()); err == nil {
+				return reflect.ValueOf(f).Convert(st).Interface()
+			}
+		}
+	}
+
+	return value
+}
+
+func isUnsigned(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func equalValues(a, b interface{}) bool {
+	if t, ok := a.(time.Time); ok {
+		if t2, ok := b.(time.Time); ok {
+			return t.Equal(t2)
+		}
+		return false
+	}
+	return a == b
+}
+
+// InCase validation if string values contain value.
+// If caseSensitive is false strings are compared with strings.EqualFold.
+// usage: InCase(false, "asc", "desc")
+func InCase(caseSensitive bool, values ...string) ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			for _, v := range values {
+				if (caseSensitive && v == s) || (!caseSensitive && strings.EqualFold(v, s)) {
+					return nil
+				}
+			}
+		}
+		return errors.Wrapf(ErrNotInScope, "%v", value)
+	}
+}
+
 // Min validation if value greater or equal then min
 func Min(min int) ValidationFunc {
 	return func(value interface{}) error {
@@ -84,6 +190,60 @@ func MinMax(min, max int) ValidationFunc {
 	}
 }
 
+// Positive validation if value greater then 0
+func Positive() ValidationFunc {
+	return signIn(1)
+}
+
+// Negative validation if value lower then 0
+func Negative() ValidationFunc {
+	return signIn(-1)
+}
+
+// NonNegative validation if value greater or equal then 0
+func NonNegative() ValidationFunc {
+	return signIn(0, 1)
+}
+
+// NonPositive validation if value lower or equal then 0
+func NonPositive() ValidationFunc {
+	return signIn(-1, 0)
+}
+
+// signIn validation if sign of numeric value is one of signs (-1, 0, 1).
+// Supported types: int, int64, float64.
+func signIn(signs ...int) ValidationFunc {
+	return func(value interface{}) error {
+		var sign int
+		switch v := value.(type) {
+		case int:
+			sign = compareZero(float64(v))
+		case int64:
+			sign = compareZero(float64(v))
+		case float64:
+			sign = compareZero(v)
+		default:
+			return errors.Wrapf(ErrNotInScope, "%v", value)
+		}
+		for _, s := range signs {
+			if s == sign {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrNotInScope, "%v", value)
+	}
+}
+
+func compareZero(v float64) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
 // NotEmpty validation if string value length more then 0
 func NotEmpty() ValidationFunc {
 	return func(value interface{}) error {
@@ -95,3 +255,206 @@ func NotEmpty() ValidationFunc {
 		return errors.Wrapf(ErrNotInScope, "%v", value)
 	}
 }
+
+// IsInteger validation if string value is an integer number.
+// Useful for string filters which can't be declared as ":int".
+func IsInteger() ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			if _, err := strconv.Atoi(s); err == nil {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrBadFormat, "%v", value)
+	}
+}
+
+// IsFloat validation if string value is a float number
+func IsFloat() ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			if _, err := strconv.ParseFloat(s, 64); err == nil {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrBadFormat, "%v", value)
+	}
+}
+
+// IsBool validation if string value is one of "true", "false", "1", "0",
+// "yes", "no" (case insensitive).
+func IsBool() ValidationFunc {
+	return isBoolOneOf("true", "false", "1", "0", "yes", "no")
+}
+
+// IsBoolStrict validation if string value is "true" or "false" (case insensitive)
+func IsBoolStrict() ValidationFunc {
+	return isBoolOneOf("true", "false")
+}
+
+// IsBoolAny validation if string value is any common boolean representation:
+// "true", "false", "t", "f", "1", "0", "yes", "no", "y", "n", "on", "off" (case insensitive).
+func IsBoolAny() ValidationFunc {
+	return isBoolOneOf("true", "false", "t", "f", "1", "0", "yes", "no", "y", "n", "on", "off")
+}
+
+func isBoolOneOf(values ...string) ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			s = strings.ToLower(s)
+			for _, v := range values {
+				if s == v {
+					return nil
+				}
+			}
+		}
+		return errors.Wrapf(ErrBadFormat, "%v", value)
+	}
+}
+
+// IsUUID validation if string value is a UUID
+func IsUUID() ValidationFunc {
+	return isUUIDVersion(0)
+}
+
+// IsUUID3 validation if string value is a UUID of version 3
+func IsUUID3() ValidationFunc {
+	return isUUIDVersion(3)
+}
+
+// IsUUID4 validation if string value is a UUID of version 4
+func IsUUID4() ValidationFunc {
+	return isUUIDVersion(4)
+}
+
+// IsUUID5 validation if string value is a UUID of version 5
+func IsUUID5() ValidationFunc {
+	return isUUIDVersion(5)
+}
+
+// isUUIDVersion checks version of UUID if version isn't 0
+func isUUIDVersion(version uuid.Version) ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			id, err := uuid.Parse(s)
+			if err == nil && (version == 0 || id.Version() == version) {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrBadFormat, "%v", value)
+	}
+}
+
+// emailPattern is a deliberately simple pattern for local@domain.tld addresses
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*\.[a-zA-Z]{2,}$`)
+
+// IsEmail validation if string value looks like an email address.
+// It's a simplification of RFC 5321: it covers the common local@domain.tld
+// form and doesn't support quoted local parts, IP address literals or
+// internationalized domain names.
+func IsEmail() ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			if len(s) <= 254 && emailPattern.MatchString(s) {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrBadFormat, "%v", value)
+	}
+}
+
+// IsURL validation if string value is an absolute URL with scheme and host
+func IsURL() ValidationFunc {
+	return isURLWithScheme("")
+}
+
+// IsHTTPS validation if string value is an absolute URL with https scheme
+func IsHTTPS() ValidationFunc {
+	return isURLWithScheme("https")
+}
+
+// isURLWithScheme checks scheme of URL if scheme isn't empty
+func isURLWithScheme(scheme string) ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			u, err := url.Parse(s)
+			if err == nil && u.Scheme != "" && u.Host != "" && (scheme == "" || u.Scheme == scheme) {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrBadFormat, "%v", value)
+	}
+}
+
+// IsISO8601 validation if string value is a timestamp in RFC3339 format
+// (eg. 2006-01-02T15:04:05Z07:00)
+func IsISO8601() ValidationFunc {
+	return isTimeLayout(time.RFC3339)
+}
+
+// IsDate validation if string value is a date in 2006-01-02 format
+func IsDate() ValidationFunc {
+	return isTimeLayout("2006-01-02")
+}
+
+// IsTime validation if string value is a time in 15:04:05 format
+func IsTime() ValidationFunc {
+	return isTimeLayout("15:04:05")
+}
+
+func isTimeLayout(layout string) ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			if _, err := time.Parse(layout, s); err == nil {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrBadFormat, "%v", value)
+	}
+}
+
+// MaxDecimalPlaces validation if float64 or numeric string value has
+// not more then n digits after the decimal point
+func MaxDecimalPlaces(n int) ValidationFunc {
+	return func(value interface{}) error {
+		var s string
+		switch v := value.(type) {
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			s = v
+		default:
+			return errors.Wrapf(ErrNotInScope, "%v", value)
+		}
+		if i := strings.IndexByte(s, '.'); i >= 0 && len(s)-i-1 > n {
+			return errors.Wrapf(ErrNotInScope, "%v", value)
+		}
+		return nil
+	}
+}
+
+// AllowedChars validation if string value contains only characters from charset
+// usage: AllowedChars("abcdefghijklmnopqrstuvwxyz0123456789-")
+func AllowedChars(charset string) ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			if strings.IndexFunc(s, func(r rune) bool { return !strings.ContainsRune(charset, r) }) < 0 {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrNotInScope, "%v", value)
+	}
+}
+
+// DisallowedChars validation if string value doesn't contain any character from charset
+// usage: DisallowedChars("%_")
+func DisallowedChars(charset string) ValidationFunc {
+	return func(value interface{}) error {
+		if s, ok := value.(string); ok {
+			if !strings.ContainsAny(s, charset) {
+				return nil
+			}
+		}
+		return errors.Wrapf(ErrNotInScope, "%v", value)
+	}
+}
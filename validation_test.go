@@ -2,7 +2,9 @@ package rqp
 
 import (
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,6 +23,67 @@ func TestIn(t *testing.T) {
 	assert.EqualError(t, err, "false: not in scope")
 }
 
+func TestInDispatch(t *testing.T) {
+	now := time.Date(2020, 10, 2, 10, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	cases := []struct {
+		name  string
+		in    []interface{}
+		value interface{}
+	}{
+		{name: "float64", in: []interface{}{1.5, 2.0}, value: "1.5"},
+		{name: "float64 from int", in: []interface{}{1.0, 2.0}, value: 2},
+		{name: "float32", in: []interface{}{float32(1.5)}, value: "1.5"},
+		{name: "int64", in: []interface{}{int64(1), int64(2)}, value: 2},
+		{name: "int32", in: []interface{}{int32(1), int32(2)}, value: 2},
+		{name: "int16", in: []interface{}{int16(1), int16(2)}, value: 2},
+		{name: "int8", in: []interface{}{int8(1), int8(2)}, value: 2},
+		{name: "uint", in: []interface{}{uint(1), uint(2)}, value: 2},
+		{name: "uint64", in: []interface{}{uint64(1), uint64(2)}, value: 2},
+		{name: "time.Time", in: []interface{}{now}, value: "2020-10-02T13:00:00+03:00"},
+		{name: "uuid.UUID", in: []interface{}{id}, value: id.String()},
+	}
+	for _, c := range cases {
+		assert.NoError(t, In(c.in...)(c.value), c.name)
+	}
+
+	// overflow and negative values must not match
+	err := In(int8(44))(300)
+	assert.Equal(t, ErrNotInScope, errors.Cause(err))
+	err = In(uint(1))(-1)
+	assert.Equal(t, ErrNotInScope, errors.Cause(err))
+	err = In(1.5)("abc")
+	assert.Equal(t, ErrNotInScope, errors.Cause(err))
+	err = In(id)("bad-uuid")
+	assert.Equal(t, ErrNotInScope, errors.Cause(err))
+
+	assert.PanicsWithValue(t, "rqp: In: mixed types int and string", func() { In(1, "two") })
+}
+
+func TestInCase(t *testing.T) {
+	// case insensitive
+	err := InCase(false, "asc", "desc")("DeSc")
+	assert.NoError(t, err)
+	err = InCase(false, "σίσυφος")("ΣΊΣΥΦΟΣ")
+	assert.NoError(t, err)
+	err = InCase(false, "kelvin")("\u212Aelvin") // Kelvin sign
+	assert.NoError(t, err)
+	err = InCase(false, "asc", "desc")("random")
+	assert.Equal(t, errors.Cause(err), ErrNotInScope)
+	assert.EqualError(t, err, "random: not in scope")
+
+	// case sensitive
+	err = InCase(true, "asc", "desc")("desc")
+	assert.NoError(t, err)
+	err = InCase(true, "asc", "desc")("DESC")
+	assert.Equal(t, errors.Cause(err), ErrNotInScope)
+
+	// not a string
+	err = InCase(false, "1")(1)
+	assert.Equal(t, errors.Cause(err), ErrNotInScope)
+}
+
 func TestMinMax(t *testing.T) {
 	err := Max(100)(101)
 	assert.Equal(t, errors.Cause(err), ErrNotInScope)
@@ -55,6 +118,28 @@ func TestMinMax(t *testing.T) {
 
 }
 
+func TestSign(t *testing.T) {
+	cases := []struct {
+		name     string
+		validate ValidationFunc
+		ok       []interface{}
+		bad      []interface{}
+	}{
+		{name: "Positive", validate: Positive(), ok: []interface{}{1, int64(1), 0.1}, bad: []interface{}{0, int64(-1), -0.1, 0.0, "1"}},
+		{name: "Negative", validate: Negative(), ok: []interface{}{-1, int64(-1), -0.1}, bad: []interface{}{0, int64(1), 0.1, "-1"}},
+		{name: "NonNegative", validate: NonNegative(), ok: []interface{}{0, int64(1), 0.1}, bad: []interface{}{-1, int64(-1), -0.1}},
+		{name: "NonPositive", validate: NonPositive(), ok: []interface{}{0, int64(-1), -0.1}, bad: []interface{}{1, int64(1), 0.1}},
+	}
+	for _, c := range cases {
+		for _, v := range c.ok {
+			assert.NoError(t, c.validate(v), "%s(%v)", c.name, v)
+		}
+		for _, v := range c.bad {
+			assert.Equal(t, ErrNotInScope, errors.Cause(c.validate(v)), "%s(%v)", c.name, v)
+		}
+	}
+}
+
 func TestNotEmpty(t *testing.T) {
 	// good case
 	err := NotEmpty()("test")
@@ -63,3 +148,155 @@ func TestNotEmpty(t *testing.T) {
 	err = NotEmpty()("")
 	assert.Equal(t, errors.Cause(err), ErrNotInScope)
 }
+
+func TestIsNumeric(t *testing.T) {
+	for _, v := range []string{"0", "-10", "42"} {
+		assert.NoError(t, IsInteger()(v), v)
+	}
+	for _, v := range []interface{}{"", "1.5", "abc", "1e3", 1} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsInteger()(v)), v)
+	}
+
+	for _, v := range []string{"0", "-10", "1.5", "1e3"} {
+		assert.NoError(t, IsFloat()(v), v)
+	}
+	for _, v := range []interface{}{"", "abc", "1,5", 1.5} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsFloat()(v)), v)
+	}
+}
+
+func TestIsBool(t *testing.T) {
+	for _, v := range []string{"true", "FALSE", "1", "0", "Yes", "no"} {
+		assert.NoError(t, IsBool()(v), v)
+	}
+	for _, v := range []interface{}{"", "on", "y", "2", true} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsBool()(v)), v)
+	}
+
+	for _, v := range []string{"true", "False"} {
+		assert.NoError(t, IsBoolStrict()(v), v)
+	}
+	for _, v := range []string{"1", "0", "yes", "no"} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsBoolStrict()(v)), v)
+	}
+
+	for _, v := range []string{"true", "F", "1", "0", "YES", "n", "on", "Off"} {
+		assert.NoError(t, IsBoolAny()(v), v)
+	}
+	for _, v := range []string{"", "maybe", "2"} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsBoolAny()(v)), v)
+	}
+}
+
+func TestIsUUID(t *testing.T) {
+	v3 := uuid.NewMD5(uuid.NameSpaceURL, []byte("rqp")).String()
+	v4 := uuid.New().String()
+	v5 := uuid.NewSHA1(uuid.NameSpaceURL, []byte("rqp")).String()
+
+	for _, v := range []string{v3, v4, v5} {
+		assert.NoError(t, IsUUID()(v), v)
+	}
+	for _, v := range []interface{}{"", "not-uuid", uuid.New()} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsUUID()(v)), v)
+	}
+
+	assert.NoError(t, IsUUID3()(v3))
+	assert.NoError(t, IsUUID4()(v4))
+	assert.NoError(t, IsUUID5()(v5))
+	assert.Equal(t, ErrBadFormat, errors.Cause(IsUUID3()(v4)))
+	assert.Equal(t, ErrBadFormat, errors.Cause(IsUUID4()(v5)))
+	assert.Equal(t, ErrBadFormat, errors.Cause(IsUUID5()(v3)))
+}
+
+func TestIsEmail(t *testing.T) {
+	good := []string{
+		"tim@example.com",
+		"first.last@example.co.uk",
+		"user+tag@sub.example.org",
+		"o'neil@example.io",
+		"a_b-c@my-domain.com",
+	}
+	for _, v := range good {
+		assert.NoError(t, IsEmail()(v), v)
+	}
+
+	bad := []interface{}{
+		"",
+		"plainaddress",
+		"@example.com",
+		"user@",
+		"user@example",
+		"user@-example.com",
+		"user@example-.com",
+		"user@example..com",
+		"user name@example.com",
+		"user@@example.com",
+		"\"quoted\"@example.com",
+		"user@[127.0.0.1]",
+		1,
+	}
+	for _, v := range bad {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsEmail()(v)), v)
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	for _, v := range []string{"http://example.com", "https://example.com/path?q=1", "ftp://files.example.com"} {
+		assert.NoError(t, IsURL()(v), v)
+	}
+	for _, v := range []interface{}{"", "example.com", "/relative/path", "http://", "mailto:tim@example.com", "://bad", 1} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsURL()(v)), v)
+	}
+
+	assert.NoError(t, IsHTTPS()("https://example.com"))
+	assert.Equal(t, ErrBadFormat, errors.Cause(IsHTTPS()("http://example.com")))
+	assert.Equal(t, ErrBadFormat, errors.Cause(IsHTTPS()("https://")))
+}
+
+func TestIsTimeLayouts(t *testing.T) {
+	for _, v := range []string{"2020-10-02T15:04:05Z", "2020-10-02T15:04:05+03:00", "2020-10-02T15:04:05.123Z"} {
+		assert.NoError(t, IsISO8601()(v), v)
+	}
+	for _, v := range []interface{}{"", "2020-10-02", "2020-10-02 15:04:05", "02/10/2020", time.Now()} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsISO8601()(v)), v)
+	}
+
+	assert.NoError(t, IsDate()("2020-10-02"))
+	for _, v := range []string{"2020-13-02", "2020-10-02T15:04:05Z", "20201002"} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsDate()(v)), v)
+	}
+
+	assert.NoError(t, IsTime()("15:04:05"))
+	for _, v := range []string{"25:00:00", "15:04", "3:04PM"} {
+		assert.Equal(t, ErrBadFormat, errors.Cause(IsTime()(v)), v)
+	}
+}
+
+func TestMaxDecimalPlaces(t *testing.T) {
+	for _, v := range []interface{}{"3.14", "3.1", "3", "3.", 3.14, 3.0, 100.5} {
+		assert.NoError(t, MaxDecimalPlaces(2)(v), v)
+	}
+	for _, v := range []interface{}{"3.14159265", "0.001", 3.141, 0.125, 3} {
+		assert.Equal(t, ErrNotInScope, errors.Cause(MaxDecimalPlaces(2)(v)), v)
+	}
+	assert.NoError(t, MaxDecimalPlaces(0)("42"))
+	assert.Equal(t, ErrNotInScope, errors.Cause(MaxDecimalPlaces(0)(4.2)))
+}
+
+func TestAllowedChars(t *testing.T) {
+	slug := AllowedChars("abcdefghijklmnopqrstuvwxyz0123456789-")
+	for _, v := range []string{"", "my-slug-42", "abc"} {
+		assert.NoError(t, slug(v), v)
+	}
+	for _, v := range []interface{}{"My-Slug", "my slug", "слаг", 42} {
+		assert.Equal(t, ErrNotInScope, errors.Cause(slug(v)), v)
+	}
+
+	assert.NoError(t, AllowedChars("αβγ")("γαβ"))
+
+	noWildcards := DisallowedChars("%_")
+	assert.NoError(t, noWildcards("tim"))
+	assert.Equal(t, ErrNotInScope, errors.Cause(noWildcards("t%m")))
+	assert.Equal(t, ErrNotInScope, errors.Cause(noWildcards("t_m")))
+	assert.Equal(t, ErrNotInScope, errors.Cause(noWildcards(1)))
+}